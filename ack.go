@@ -0,0 +1,36 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// PublishAck publishes a message like Publish and returns a channel that is closed once
+// every handler for it has finished. On an asynchronous PubSub this waits for the
+// topic's worker, so callers can synchronize with a single message without draining
+// the whole PubSub. The channel is also closed if the message is dropped, or buffered
+// by a paused topic, and right away if the topic has no handlers.
+func (p *pubsub) PublishAck(topic string, args ...any) (<-chan struct{}, error) {
+	acked := make(chan struct{})
+	var ack sync.WaitGroup
+	err := p.publish(context.Background(), topic, append([]any(nil), args...), false, &ack)
+	go func() {
+		ack.Wait()
+		close(acked)
+	}()
+	return acked, err
+}
+
+// queued is a message in the queue of an asynchronous topic.
+type queued struct {
+	args []any
+	// ack, if not nil, is done once the message has been delivered or discarded.
+	ack *sync.WaitGroup
+}
+
+// done records that the message has been delivered or discarded.
+func (m queued) done() {
+	if m.ack != nil {
+		m.ack.Done()
+	}
+}
@@ -0,0 +1,88 @@
+package pubsub
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPublishAck(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"sync":         nil,
+		"async":        {WithAsync(8)},
+		"global order": {WithAsync(8), WithGlobalOrder(false)},
+	} {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			ps := New(opts...)
+			defer ps.Shutdown()
+			var finished atomic.Int32
+			for i := 0; i < 2; i++ {
+				err := ps.Subscribe("testTopic", func(args ...any) {
+					time.Sleep(10 * time.Millisecond)
+					finished.Add(1)
+				})
+				if err != nil {
+					t.Fatalf("Subscribe returned an error: %s", err.Error())
+				}
+			}
+
+			acked, err := ps.PublishAck("testTopic", "payload")
+			if err != nil {
+				t.Fatalf("PublishAck returned an error: %s", err.Error())
+			}
+
+			select {
+			case <-acked:
+			case <-time.After(time.Second):
+				t.Fatal("ack channel was not closed")
+			}
+			if n := finished.Load(); n != 2 {
+				t.Fatalf("expected both handlers to have finished before the ack, got %d", n)
+			}
+		})
+	}
+}
+
+func TestPublishAckNoHandlers(t *testing.T) {
+	ps := New(WithAsync(8))
+	defer ps.Shutdown()
+
+	acked, err := ps.PublishAck("testTopic", "payload")
+	if err != nil {
+		t.Fatalf("PublishAck returned an error: %s", err.Error())
+	}
+
+	select {
+	case <-acked:
+	case <-time.After(time.Second):
+		t.Fatal("ack channel was not closed")
+	}
+}
+
+func TestPublishAckDropped(t *testing.T) {
+	ps := New(WithAsync(0), WithOverflow(OverflowDropNewest, nil))
+	defer ps.Shutdown()
+	release := make(chan struct{})
+	err := ps.Subscribe("testTopic", func(args ...any) {
+		<-release
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic", "blocking"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	defer close(release)
+
+	acked, err := ps.PublishAck("testTopic", "dropped")
+	if err != nil {
+		t.Fatalf("PublishAck returned an error: %s", err.Error())
+	}
+
+	select {
+	case <-acked:
+	case <-time.After(time.Second):
+		t.Fatal("ack channel of a dropped message was not closed")
+	}
+}
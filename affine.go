@@ -0,0 +1,11 @@
+package pubsub
+
+// SubscribeAffine adds a handler to the topic that is registered only on the shard the
+// exact topic name hashes to (see WithShards). Publishing to the topic then only locks
+// that shard, so publishes and subscriptions to topics on other shards never contend
+// with it. Topics are matched by their exact name, so no other shard is ever visited
+// to find the handler.
+func (p *pubsub) SubscribeAffine(topic string, handler func(...any)) error {
+	_, err := p.subscribe(topic, &subscription{handler: handler})
+	return err
+}
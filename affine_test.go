@@ -0,0 +1,40 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribeAffine(t *testing.T) {
+	ps := New(WithShards(8))
+	var got []any
+	if err := ps.SubscribeAffine("testTopic", func(args ...any) { got = args }); err != nil {
+		t.Fatalf("SubscribeAffine returned an error: %s", err.Error())
+	}
+
+	// Lock every shard but the topic's; a publish that touched any of them would block.
+	p := ps.(*pubsub)
+	own := p.shard("testTopic")
+	for i := range p.shards {
+		if sh := &p.shards[i]; sh != own {
+			sh.mu.Lock()
+			defer sh.mu.Unlock()
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ps.Publish("testTopic", "hello")
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Publish to only lock the topic's own shard")
+	}
+	if len(got) != 1 || got[0] != "hello" {
+		t.Fatalf("expected the affine handler to receive the message, got %v", got)
+	}
+}
@@ -0,0 +1,68 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrAliasCycle is returned by Alias when the alias would make a topic an alias of itself.
+var ErrAliasCycle = errors.New("pubsub: alias cycle")
+
+// Alias makes every publish to the topic from also deliver the message to the handlers
+// of the topic to. The direction is one-way: publishes to to don't reach the handlers of
+// from. Aliases can be chained, in which case a publish is delivered along the whole chain,
+// but not made circular. An existing alias of from is replaced.
+// PublishIfSubscribed delivers to each topic of the chain that has handlers.
+func (p *pubsub) Alias(from, to string) error {
+	from, err := p.topicName(from)
+	if err != nil {
+		return err
+	}
+	to, err = p.topicName(to)
+	if err != nil {
+		return err
+	}
+	p.aliasesMu.Lock()
+	defer p.aliasesMu.Unlock()
+	for name, ok := to, true; ok; name, ok = p.aliases[name] {
+		if name == from {
+			return fmt.Errorf("%w: %q already leads to %q", ErrAliasCycle, to, from)
+		}
+	}
+	if p.aliases == nil {
+		p.aliases = make(map[string]string)
+	}
+	p.aliases[from] = to
+	return nil
+}
+
+// RemoveAlias removes the alias of the topic from, if there is one.
+func (p *pubsub) RemoveAlias(from string) error {
+	from, err := p.topicName(from)
+	if err != nil {
+		return err
+	}
+	p.aliasesMu.Lock()
+	defer p.aliasesMu.Unlock()
+	delete(p.aliases, from)
+	return nil
+}
+
+// aliased returns the topics a publish to the topic is also delivered to, in chain order.
+func (p *pubsub) aliased(topic string) []string {
+	p.aliasesMu.RLock()
+	defer p.aliasesMu.RUnlock()
+	var targets []string
+	for to, ok := p.aliases[topic]; ok; to, ok = p.aliases[to] {
+		targets = append(targets, to)
+	}
+	return targets
+}
+
+// hasAlias reports whether the topic is an alias.
+func (p *pubsub) hasAlias(topic string) bool {
+	p.aliasesMu.RLock()
+	defer p.aliasesMu.RUnlock()
+	_, ok := p.aliases[topic]
+	return ok
+}
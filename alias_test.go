@@ -0,0 +1,85 @@
+package pubsub
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAlias(t *testing.T) {
+	ps := New()
+	var received []string
+	for _, topic := range []string{"orders", "orders.v2", "orders.v3"} {
+		topic := topic
+		if err := ps.Subscribe(topic, func(args ...any) {
+			received = append(received, topic+":"+args[0].(string))
+		}); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	if err := ps.Alias("orders", "orders.v2"); err != nil {
+		t.Fatalf("Alias returned an error: %s", err.Error())
+	}
+	if err := ps.Alias("orders.v2", "orders.v3"); err != nil {
+		t.Fatalf("Alias returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("orders", "a"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	// Aliases are one-way.
+	if err := ps.Publish("orders.v3", "b"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	want := []string{"orders:a", "orders.v2:a", "orders.v3:a", "orders.v3:b"}
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("expected %v, got %v", want, received)
+	}
+
+	received = nil
+	if err := ps.RemoveAlias("orders"); err != nil {
+		t.Fatalf("RemoveAlias returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("orders", "c"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if want := []string{"orders:c"}; !reflect.DeepEqual(received, want) {
+		t.Errorf("expected %v after RemoveAlias, got %v", want, received)
+	}
+}
+
+func TestAliasWithoutHandlers(t *testing.T) {
+	ps := New()
+	var received []any
+	if err := ps.Subscribe("new", func(args ...any) {
+		received = append(received, args[0])
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Alias("old", "new"); err != nil {
+		t.Fatalf("Alias returned an error: %s", err.Error())
+	}
+	// The alias has no handlers and doesn't even exist as a topic.
+	if err := ps.Publish("old", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(received) != 1 || received[0] != 1 {
+		t.Errorf("expected the target's handler to receive the message, got %v", received)
+	}
+}
+
+func TestAliasCycle(t *testing.T) {
+	ps := New()
+	if err := ps.Alias("a", "a"); !errors.Is(err, ErrAliasCycle) {
+		t.Errorf("expected ErrAliasCycle for an alias of itself, got %v", err)
+	}
+	if err := ps.Alias("a", "b"); err != nil {
+		t.Fatalf("Alias returned an error: %s", err.Error())
+	}
+	if err := ps.Alias("b", "c"); err != nil {
+		t.Fatalf("Alias returned an error: %s", err.Error())
+	}
+	if err := ps.Alias("c", "a"); !errors.Is(err, ErrAliasCycle) {
+		t.Errorf("expected ErrAliasCycle, got %v", err)
+	}
+}
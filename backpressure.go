@@ -0,0 +1,30 @@
+package pubsub
+
+import "time"
+
+// backpressureInterval is the minimum time between two backpressure callbacks for a topic.
+const backpressureInterval = 100 * time.Millisecond
+
+// checkBackpressure calls the backpressure callback if the topic's queue is at or above
+// its high-water mark of three quarters of its capacity, at most once per interval.
+func (t *topic) checkBackpressure() {
+	cb := t.p.opts.backpressure
+	capacity := cap(t.queue)
+	if cb == nil || capacity == 0 {
+		return
+	}
+	highWater := capacity * 3 / 4
+	if highWater < 1 {
+		highWater = 1
+	}
+	queueLen := len(t.queue)
+	if queueLen < highWater {
+		return
+	}
+	now := t.p.opts.clock.Now().UnixNano()
+	last := t.lastBackpressure.Load()
+	if now-last < int64(backpressureInterval) || !t.lastBackpressure.CompareAndSwap(last, now) {
+		return
+	}
+	cb(t.name, queueLen, capacity)
+}
@@ -0,0 +1,53 @@
+package pubsub
+
+import (
+	"testing"
+)
+
+func TestBackpressure(t *testing.T) {
+	type call struct {
+		topic              string
+		queueLen, capacity int
+	}
+	var calls []call
+	ps := New(WithAsync(4), WithBackpressure(func(topic string, queueLen, capacity int) {
+		calls = append(calls, call{topic, queueLen, capacity})
+	}))
+	defer ps.Shutdown()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	err := ps.Subscribe("testTopic", func(args ...any) {
+		if args[0] == 0 {
+			close(started)
+			<-release
+		}
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	// Block the worker, then fill the queue.
+	if err := ps.Publish("testTopic", 0); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	<-started
+	for i := 1; i <= 4; i++ {
+		if err := ps.Publish("testTopic", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if err := ps.TryPublish("testTopic", 5); err == nil {
+		t.Fatal("expected the queue to be full")
+	}
+	close(release)
+
+	// The queue reaches the high-water mark of 3 on the fourth publish; the
+	// full-queue TryPublish is throttled.
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 backpressure call, got %v", calls)
+	}
+	if c := calls[0]; c.topic != "testTopic" || c.queueLen != 3 || c.capacity != 4 {
+		t.Errorf("expected testTopic at 3 of 4, got %+v", c)
+	}
+}
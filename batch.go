@@ -0,0 +1,113 @@
+package pubsub
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrInvalidBatch is returned by SubscribeBatch when neither a batch size nor a wait is
+// set, as batches would then never be delivered.
+var ErrInvalidBatch = errors.New("pubsub: batch is never delivered")
+
+// SubscribeBatch adds a handler to the topic that receives messages in batches instead of
+// one at a time. Published messages are accumulated and handed to the handler, in order,
+// once maxBatch of them are pending or maxWait has passed since the first of them arrived,
+// whichever comes first. A maxBatch or maxWait of zero or less disables that trigger, but
+// disabling both returns ErrInvalidBatch.
+// Full batches are delivered from the publishing goroutine and timed out ones from a timer
+// goroutine, but the handler is never called concurrently with itself, so it must not
+// publish to the topic or cancel the subscription, as it would wait for itself. When it is
+// removed, by the returned cancel function, Unsubscribe or Shutdown, the remaining partial
+// batch is delivered before that returns.
+func (p *pubsub) SubscribeBatch(topic string, maxBatch int, maxWait time.Duration, handler func(batch [][]any)) (cancel func(), err error) {
+	if handler == nil {
+		return nil, ErrNilHandler
+	}
+	if maxBatch <= 0 && maxWait <= 0 {
+		return nil, ErrInvalidBatch
+	}
+	b := &batcher{clock: p.opts.clock, maxBatch: maxBatch, maxWait: maxWait, handler: handler}
+	return p.subscribe(topic, &subscription{handler: b.receive, stop: b.stop})
+}
+
+// batcher accumulates messages until a batch is full or has waited long enough.
+type batcher struct {
+	clock    Clock
+	maxBatch int
+	maxWait  time.Duration
+	handler  func(batch [][]any)
+	// callMu serializes the calls of handler.
+	callMu sync.Mutex
+
+	// mu guards the fields below.
+	mu    sync.Mutex
+	batch [][]any
+	timer Timer
+	// gen counts the batches started, so a timer fires only for the batch it was set for.
+	gen     uint64
+	stopped bool
+}
+
+func (b *batcher) receive(args ...any) {
+	b.callMu.Lock()
+	defer b.callMu.Unlock()
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return
+	}
+	b.batch = append(b.batch, args)
+	if len(b.batch) == 1 && b.maxWait > 0 {
+		b.gen++
+		gen := b.gen
+		b.timer = b.clock.AfterFunc(b.maxWait, func() { b.fire(gen) })
+	}
+	var batch [][]any
+	if b.maxBatch > 0 && len(b.batch) >= b.maxBatch {
+		batch = b.take()
+	}
+	b.mu.Unlock()
+	if batch != nil {
+		b.handler(batch)
+	}
+}
+
+// take returns the pending batch and starts a new one. It must be called with mu held.
+func (b *batcher) take() [][]any {
+	batch := b.batch
+	b.batch = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return batch
+}
+
+// fire delivers the batch numbered gen if it is still pending.
+func (b *batcher) fire(gen uint64) {
+	b.callMu.Lock()
+	defer b.callMu.Unlock()
+	b.mu.Lock()
+	var batch [][]any
+	if gen == b.gen && len(b.batch) > 0 {
+		batch = b.take()
+	}
+	b.mu.Unlock()
+	if batch != nil {
+		b.handler(batch)
+	}
+}
+
+// stop delivers the pending partial batch, if any, and ignores later messages.
+func (b *batcher) stop() {
+	b.callMu.Lock()
+	defer b.callMu.Unlock()
+	b.mu.Lock()
+	b.stopped = true
+	batch := b.take()
+	b.mu.Unlock()
+	if len(batch) > 0 {
+		b.handler(batch)
+	}
+}
@@ -0,0 +1,112 @@
+package pubsub
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubscribeBatchSize(t *testing.T) {
+	ps := New()
+	var batches [][][]any
+	cancel, err := ps.SubscribeBatch("testTopic", 3, time.Hour, func(batch [][]any) {
+		batches = append(batches, batch)
+	})
+	if err != nil {
+		t.Fatalf("SubscribeBatch returned an error: %s", err.Error())
+	}
+	defer cancel()
+
+	for i := 0; i < 7; i++ {
+		if err := ps.Publish("testTopic", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+
+	want := [][][]any{{{0}, {1}, {2}}, {{3}, {4}, {5}}}
+	if !reflect.DeepEqual(batches, want) {
+		t.Fatalf("expected full batches %v, got %v", want, batches)
+	}
+}
+
+func TestSubscribeBatchWait(t *testing.T) {
+	ps := New()
+	var mu sync.Mutex
+	var batches [][][]any
+	flushed := make(chan struct{}, 1)
+	cancel, err := ps.SubscribeBatch("testTopic", 100, 20*time.Millisecond, func(batch [][]any) {
+		mu.Lock()
+		batches = append(batches, batch)
+		mu.Unlock()
+		flushed <- struct{}{}
+	})
+	if err != nil {
+		t.Fatalf("SubscribeBatch returned an error: %s", err.Error())
+	}
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		if err := ps.Publish("testTopic", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Fatal("expected the partial batch to be flushed after maxWait")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := [][][]any{{{0}, {1}}}
+	if !reflect.DeepEqual(batches, want) {
+		t.Fatalf("expected %v, got %v", want, batches)
+	}
+}
+
+func TestSubscribeBatchFlushOnCancel(t *testing.T) {
+	for name, remove := range map[string]func(ps PubSub, cancel func()){
+		"cancel":   func(ps PubSub, cancel func()) { cancel() },
+		"shutdown": func(ps PubSub, cancel func()) { _ = ps.Shutdown() },
+	} {
+		remove := remove
+		t.Run(name, func(t *testing.T) {
+			ps := New()
+			var batches [][][]any
+			cancel, err := ps.SubscribeBatch("testTopic", 10, time.Hour, func(batch [][]any) {
+				batches = append(batches, batch)
+			})
+			if err != nil {
+				t.Fatalf("SubscribeBatch returned an error: %s", err.Error())
+			}
+			if err := ps.Publish("testTopic", "partial"); err != nil {
+				t.Fatalf("Publish returned an error: %s", err.Error())
+			}
+
+			remove(ps, cancel)
+
+			want := [][][]any{{{"partial"}}}
+			if !reflect.DeepEqual(batches, want) {
+				t.Fatalf("expected the partial batch %v to be flushed, got %v", want, batches)
+			}
+			if err := ps.Publish("testTopic", "late"); err != nil && !errors.Is(err, ErrShutdown) {
+				t.Fatalf("Publish returned an error: %s", err.Error())
+			}
+			if len(batches) != 1 {
+				t.Fatalf("expected no batch after removal, got %v", batches)
+			}
+		})
+	}
+}
+
+func TestSubscribeBatchInvalid(t *testing.T) {
+	ps := New()
+	if _, err := ps.SubscribeBatch("testTopic", 0, 0, func(batch [][]any) {}); !errors.Is(err, ErrInvalidBatch) {
+		t.Errorf("expected ErrInvalidBatch without a batch size or wait, got %v", err)
+	}
+	if ps.HasTopic("testTopic") {
+		t.Error("expected no topic to be created")
+	}
+}
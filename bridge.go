@@ -0,0 +1,157 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrBridgeCycle is returned by Bridge when bridging would forward messages in a circle,
+// such as from a PubSub to itself.
+var ErrBridgeCycle = errors.New("pubsub: bridge cycle")
+
+// bridgesMu guards the bridges of all PubSubs, as a circle of bridges spans several of them.
+var bridgesMu sync.Mutex
+
+// Bridge forwards the messages published to the topics of src to the same topics of dst,
+// so handlers subscribed to dst also receive them. With no topics, every publish to src is
+// forwarded, to current and future topics alike, whether or not src has handlers for it;
+// this requires src to be created by New or Namespace. Errors of publishing to dst are
+// ignored. Bridges can be chained but not made circular: bridging a PubSub, or a namespace
+// of it, to itself or to a PubSub that already forwards to it returns ErrBridgeCycle.
+// The returned cancel function stops forwarding.
+func Bridge(dst, src PubSub, topics ...string) (cancel func(), err error) {
+	srcRoot, srcPrefix := rootOf(src)
+	if len(topics) == 0 && srcRoot == nil {
+		return nil, fmt.Errorf("pubsub: bridging all topics of a %T is not supported", src)
+	}
+	dstRoot, _ := rootOf(dst)
+	if dst == src || srcRoot != nil && srcRoot == dstRoot {
+		return nil, fmt.Errorf("%w: bridging a PubSub to itself", ErrBridgeCycle)
+	}
+	if srcRoot != nil && dstRoot != nil {
+		if err := addBridge(srcRoot, dstRoot); err != nil {
+			return nil, err
+		}
+	}
+	removeBridge := func() {
+		if srcRoot != nil && dstRoot != nil {
+			dropBridge(srcRoot, dstRoot)
+		}
+	}
+
+	if len(topics) > 0 {
+		unsubscribe, err := src.SubscribeMerged(topics, func(topic string, args ...any) {
+			_ = dst.Publish(topic, args...)
+		})
+		if err != nil {
+			removeBridge()
+			return nil, err
+		}
+		return func() {
+			unsubscribe()
+			removeBridge()
+		}, nil
+	}
+	removeTap := srcRoot.addTap(func(topic string, args []any) {
+		if topic, ok := strings.CutPrefix(topic, srcPrefix); ok {
+			_ = dst.Publish(topic, args...)
+		}
+	})
+	return func() {
+		removeTap()
+		removeBridge()
+	}, nil
+}
+
+// rootOf returns the PubSub created by New that ps is or is a namespace of, with the
+// prefix of the namespace, or nil if ps wasn't created by this package.
+func rootOf(ps PubSub) (*pubsub, string) {
+	switch ps := ps.(type) {
+	case *pubsub:
+		return ps, ""
+	case *namespace:
+		return ps.p, ps.prefix
+	}
+	return nil, ""
+}
+
+// addBridge records a bridge from src to dst, unless dst already forwards to src.
+func addBridge(src, dst *pubsub) error {
+	bridgesMu.Lock()
+	defer bridgesMu.Unlock()
+	if forwardsTo(dst, src, make(map[*pubsub]bool)) {
+		return fmt.Errorf("%w: the destination already forwards to the source", ErrBridgeCycle)
+	}
+	if src.bridges == nil {
+		src.bridges = make(map[*pubsub]int)
+	}
+	src.bridges[dst]++
+	return nil
+}
+
+// dropBridge removes a bridge recorded by addBridge.
+func dropBridge(src, dst *pubsub) {
+	bridgesMu.Lock()
+	defer bridgesMu.Unlock()
+	if src.bridges[dst]--; src.bridges[dst] == 0 {
+		delete(src.bridges, dst)
+	}
+}
+
+// forwardsTo reports whether from forwards to to through bridges. It must be called with
+// bridgesMu held.
+func forwardsTo(from, to *pubsub, seen map[*pubsub]bool) bool {
+	if from == to {
+		return true
+	}
+	seen[from] = true
+	for next := range from.bridges {
+		if !seen[next] && forwardsTo(next, to, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// tap is a function called with every message published to a PubSub.
+type tap struct {
+	fn func(topic string, args []any)
+}
+
+// addTap makes p call fn with every message published to it and returns a function
+// that stops it.
+func (p *pubsub) addTap(fn func(topic string, args []any)) (remove func()) {
+	t := &tap{fn: fn}
+	p.tapsMu.Lock()
+	p.taps = append(append([]*tap(nil), p.taps...), t)
+	p.tapsMu.Unlock()
+	return func() {
+		p.tapsMu.Lock()
+		defer p.tapsMu.Unlock()
+		for i, other := range p.taps {
+			if other == t {
+				p.taps = append(append([]*tap(nil), p.taps[:i]...), p.taps[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// tapped reports whether p has taps.
+func (p *pubsub) tapped() bool {
+	p.tapsMu.RLock()
+	defer p.tapsMu.RUnlock()
+	return len(p.taps) > 0
+}
+
+// callTaps calls the taps of p with a message published to the topic.
+func (p *pubsub) callTaps(topic string, args []any) {
+	p.tapsMu.RLock()
+	taps := p.taps
+	p.tapsMu.RUnlock()
+	for _, t := range taps {
+		t.fn(topic, args)
+	}
+}
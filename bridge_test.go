@@ -0,0 +1,120 @@
+package pubsub
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBridgeTopics(t *testing.T) {
+	src, dst := New(), New()
+	var received []any
+	if err := dst.Subscribe("orders", func(args ...any) { received = args }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := dst.Subscribe("other", func(args ...any) { t.Error("unbridged topic was forwarded") }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	cancel, err := Bridge(dst, src, "orders")
+	if err != nil {
+		t.Fatalf("Bridge returned an error: %s", err.Error())
+	}
+
+	if err := src.Publish("orders", "payload", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if err := src.Publish("other", "payload"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+
+	if !reflect.DeepEqual(received, []any{"payload", 1}) {
+		t.Fatalf("expected the message to be forwarded, got %v", received)
+	}
+	cancel()
+	received = nil
+	if err := src.Publish("orders", "late"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if received != nil {
+		t.Fatalf("expected no forwarding after cancel, got %v", received)
+	}
+}
+
+func TestBridgeAllTopics(t *testing.T) {
+	src, dst := New(), New()
+	received := make(map[string][]any)
+	for _, topic := range []string{"a", "b"} {
+		topic := topic
+		if err := dst.Subscribe(topic, func(args ...any) { received[topic] = args }); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+	cancel, err := Bridge(dst, src)
+	if err != nil {
+		t.Fatalf("Bridge returned an error: %s", err.Error())
+	}
+	defer cancel()
+
+	// Neither topic exists on src, so both count as future topics.
+	for _, topic := range []string{"a", "b"} {
+		if err := src.Publish(topic, topic); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+
+	want := map[string][]any{"a": {"a"}, "b": {"b"}}
+	if !reflect.DeepEqual(received, want) {
+		t.Fatalf("expected %v, got %v", want, received)
+	}
+}
+
+func TestBridgeNamespace(t *testing.T) {
+	root, dst := New(), New()
+	var received []any
+	if err := dst.Subscribe("orders", func(args ...any) { received = args }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	cancel, err := Bridge(dst, root.Namespace("billing"))
+	if err != nil {
+		t.Fatalf("Bridge returned an error: %s", err.Error())
+	}
+	defer cancel()
+
+	if err := root.Publish("orders", "outside"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if err := root.Publish("billing.orders", "inside"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+
+	if !reflect.DeepEqual(received, []any{"inside"}) {
+		t.Fatalf("expected only the namespace's message without the prefix, got %v", received)
+	}
+}
+
+func TestBridgeCycle(t *testing.T) {
+	a, b, c := New(), New(), New()
+	if _, err := Bridge(a, a); !errors.Is(err, ErrBridgeCycle) {
+		t.Fatalf("expected bridging a PubSub to itself to return ErrBridgeCycle, got %v", err)
+	}
+	if _, err := Bridge(a.Namespace("x"), a, "topic"); !errors.Is(err, ErrBridgeCycle) {
+		t.Fatalf("expected bridging a PubSub to its namespace to return ErrBridgeCycle, got %v", err)
+	}
+	cancelAB, err := Bridge(b, a)
+	if err != nil {
+		t.Fatalf("Bridge returned an error: %s", err.Error())
+	}
+	if _, err := Bridge(c, b, "topic"); err != nil {
+		t.Fatalf("Bridge returned an error: %s", err.Error())
+	}
+	if _, err := Bridge(a, c); !errors.Is(err, ErrBridgeCycle) {
+		t.Fatalf("expected closing the circle to return ErrBridgeCycle, got %v", err)
+	}
+
+	cancelAB()
+	cancelCA, err := Bridge(a, c)
+	if err != nil {
+		t.Fatalf("expected bridging to be allowed once the circle is broken, got %s", err.Error())
+	}
+	cancelCA()
+}
@@ -0,0 +1,54 @@
+package pubsub
+
+import "fmt"
+
+// Builder declares the subscriptions of a PubSub up front and creates it with Build,
+// so the wiring of an application lives in one place and can be inspected in tests.
+type Builder struct {
+	opts []Option
+	subs []builderSub
+}
+
+type builderSub struct {
+	topic   string
+	handler func(...any)
+	once    bool
+}
+
+// NewBuilder returns a Builder for a PubSub created with the given options.
+func NewBuilder(opts ...Option) *Builder {
+	return &Builder{opts: opts}
+}
+
+// On adds a handler to the topic when the PubSub is built.
+func (b *Builder) On(topic string, handler func(...any)) *Builder {
+	b.subs = append(b.subs, builderSub{topic: topic, handler: handler})
+	return b
+}
+
+// Once adds a handler to the topic that is removed after the first call, like SubscribeOnce,
+// when the PubSub is built.
+func (b *Builder) Once(topic string, handler func(...any)) *Builder {
+	b.subs = append(b.subs, builderSub{topic: topic, handler: handler, once: true})
+	return b
+}
+
+// Build creates the PubSub and adds the handlers in the order they were declared. If any
+// of them can't be added, for example because it is nil, the PubSub is shut down and the
+// error is returned.
+func (b *Builder) Build() (PubSub, error) {
+	ps := New(b.opts...)
+	for i, sub := range b.subs {
+		var err error
+		if sub.once {
+			_, err = ps.SubscribeOnce(sub.topic, sub.handler)
+		} else {
+			err = ps.Subscribe(sub.topic, sub.handler)
+		}
+		if err != nil {
+			_ = ps.Shutdown()
+			return nil, fmt.Errorf("pubsub: subscription %d to topic %q: %w", i, sub.topic, err)
+		}
+	}
+	return ps, nil
+}
@@ -0,0 +1,66 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	var orders1, orders2, boot int
+	ps, err := NewBuilder().
+		On("orders", func(args ...any) { orders1 += args[0].(int) }).
+		On("orders", func(args ...any) { orders2 += args[0].(int) }).
+		Once("boot", func(args ...any) { boot++ }).
+		Build()
+	if err != nil {
+		t.Fatalf("Build returned an error: %s", err.Error())
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := ps.Publish("orders", 5); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+		if err := ps.Publish("boot"); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+
+	if orders1 != 10 || orders2 != 10 {
+		t.Fatalf("expected both orders handlers to receive 10, got %d and %d", orders1, orders2)
+	}
+	if boot != 1 {
+		t.Fatalf("expected the boot handler to be called once, got %d", boot)
+	}
+}
+
+func TestBuilderOptions(t *testing.T) {
+	var got []any
+	ps, err := NewBuilder(WithHistory(1)).
+		On("a", func(args ...any) { got = append(got, args...) }).
+		Build()
+	if err != nil {
+		t.Fatalf("Build returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("a", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(got) != 1 || got[0] != 1 {
+		t.Fatalf("expected [1], got %v", got)
+	}
+	if history := ps.History("a", 1); len(history) != 1 {
+		t.Fatalf("expected the options to be applied, got history %v", history)
+	}
+}
+
+func TestBuilderNilHandler(t *testing.T) {
+	ps, err := NewBuilder().
+		On("a", func(...any) {}).
+		On("b", nil).
+		Build()
+	if !errors.Is(err, ErrNilHandler) {
+		t.Fatalf("expected ErrNilHandler, got %v", err)
+	}
+	if ps != nil {
+		t.Fatalf("expected no PubSub, got %v", ps)
+	}
+}
@@ -0,0 +1,131 @@
+package pubsub
+
+import (
+	"errors"
+	"sort"
+)
+
+// SubscribeMany adds the handler to all of the topics atomically: if it can't be added to
+// one of them, for example because it is closed, the error is returned and the handler
+// isn't added to any topic.
+// The returned cancel function removes the handler from all of the topics.
+func (p *pubsub) SubscribeMany(topics []string, handler func(...any)) (cancel func(), err error) {
+	if handler == nil {
+		return nil, ErrNilHandler
+	}
+	return p.subscribeAll(topics, func(string) func(...any) {
+		return handler
+	})
+}
+
+// SubscribeMerged adds the handler to all of the topics like SubscribeMany, and calls it
+// with the name of the topic each message was published to.
+// The returned cancel function removes the handler from all of the topics.
+func (p *pubsub) SubscribeMerged(topics []string, handler func(topic string, args ...any)) (cancel func(), err error) {
+	if handler == nil {
+		return nil, ErrNilHandler
+	}
+	return p.subscribeAll(topics, func(topic string) func(...any) {
+		return func(args ...any) {
+			handler(topic, args...)
+		}
+	})
+}
+
+// subscribeAll atomically adds the handler returned by handlerFor to each of the topics.
+func (p *pubsub) subscribeAll(topics []string, handlerFor func(topic string) func(...any)) (cancel func(), err error) {
+	names := make([]string, 0, len(topics))
+	seen := make(map[string]bool, len(topics))
+	for _, name := range topics {
+		name, err := p.topicName(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.known(name); err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	// Lock the topics in name order so concurrent calls can't deadlock.
+	sort.Strings(names)
+	ts, unlock, err := p.lockForAdd(names)
+	if err != nil {
+		return nil, err
+	}
+
+	subs := make([]*subscription, len(ts))
+	handlers := make([]int, len(ts))
+	for i, t := range ts {
+		subs[i] = &subscription{handler: handlerFor(t.name)}
+		t.add(subs[i])
+		handlers[i] = t.subs.len()
+	}
+	unlock()
+	for i, t := range ts {
+		p.systemEvent(SubscriberAdded, t.name, handlers[i])
+	}
+	return func() {
+		for i, t := range ts {
+			t.remove(subs[i])
+		}
+	}, nil
+}
+
+// lockForAdd locks the named topics, creating them if needed, once subscriptions can be
+// added to all of them. Like subscribe, it starts over if one of them was closed because
+// it was removed, so the subscriptions aren't added to a topic that is gone.
+func (p *pubsub) lockForAdd(names []string) (ts []*topic, unlock func(), err error) {
+	ts = make([]*topic, len(names))
+	for {
+		for i, name := range names {
+			ts[i] = p.getOrCreateTopic(name)
+		}
+		for _, t := range ts {
+			t.mu.Lock()
+		}
+		unlock = func() {
+			for _, t := range ts {
+				t.mu.Unlock()
+			}
+		}
+		err = nil
+		for _, t := range ts {
+			if err = t.canAdd(); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			return ts, unlock, nil
+		}
+		unlock()
+		if err != ErrTopicClosed || !p.anyRemoved(ts) {
+			return nil, nil, err
+		}
+	}
+}
+
+// anyRemoved reports whether any of ts is no longer the topic registered under its name.
+func (p *pubsub) anyRemoved(ts []*topic) bool {
+	for _, t := range ts {
+		if p.removed(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// PublishMany calls all handlers for each of the topics in order.
+// As with Publish, closed and unknown topics are skipped. A failure on one topic
+// doesn't stop delivery to the others; all errors are returned joined.
+func (p *pubsub) PublishMany(topics []string, args ...any) error {
+	var errs []error
+	for _, topic := range topics {
+		if err := p.Publish(topic, args...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
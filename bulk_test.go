@@ -0,0 +1,89 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSubscribeMany(t *testing.T) {
+	ps := New()
+	var received []any
+	cancel, err := ps.SubscribeMany([]string{"a", "b", "c"}, func(args ...any) {
+		received = append(received, args[0])
+	})
+	if err != nil {
+		t.Fatalf("SubscribeMany returned an error: %s", err.Error())
+	}
+
+	if err := ps.PublishMany([]string{"a", "b", "unknown"}, "x"); err != nil {
+		t.Fatalf("PublishMany returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("c", "y"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(received) != 3 || received[0] != "x" || received[1] != "x" || received[2] != "y" {
+		t.Errorf("expected [x x y], got %v", received)
+	}
+
+	cancel()
+	if err := ps.PublishMany([]string{"a", "b", "c"}, "z"); err != nil {
+		t.Fatalf("PublishMany returned an error: %s", err.Error())
+	}
+	if len(received) != 3 {
+		t.Errorf("Handler was called after cancel: %v", received)
+	}
+}
+
+func TestSubscribeManyRollback(t *testing.T) {
+	ps := New()
+	if err := ps.Subscribe("closed", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.CloseTopic("closed"); err != nil {
+		t.Fatalf("CloseTopic returned an error: %s", err.Error())
+	}
+
+	called := false
+	_, err := ps.SubscribeMany([]string{"open", "closed"}, func(args ...any) {
+		called = true
+	})
+	if !errors.Is(err, ErrTopicClosed) {
+		t.Fatalf("expected ErrTopicClosed, got %v", err)
+	}
+
+	if err := ps.Publish("open", "x"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if called {
+		t.Error("Handler was added to the open topic despite the failure")
+	}
+}
+
+func TestSubscribeMerged(t *testing.T) {
+	ps := New()
+	var received []string
+	cancel, err := ps.SubscribeMerged([]string{"orders", "payments"}, func(topic string, args ...any) {
+		received = append(received, topic+":"+args[0].(string))
+	})
+	if err != nil {
+		t.Fatalf("SubscribeMerged returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("payments", "p1"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("orders", "o1"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(received) != 2 || received[0] != "payments:p1" || received[1] != "orders:o1" {
+		t.Errorf("expected [payments:p1 orders:o1], got %v", received)
+	}
+
+	cancel()
+	if err := ps.PublishMany([]string{"orders", "payments"}, "x"); err != nil {
+		t.Fatalf("PublishMany returned an error: %s", err.Error())
+	}
+	if len(received) != 2 {
+		t.Errorf("Handler was called after cancel: %v", received)
+	}
+}
@@ -0,0 +1,59 @@
+package pubsub
+
+import "time"
+
+// Clock is the source of time of a PubSub, which delayed publishes, redelivery, rate limits,
+// topic expiry and the other time-dependent features use. It exists so tests can replace
+// the real clock with one they advance by hand. See WithClock.
+type Clock interface {
+	Now() time.Time
+	// After waits for d to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+	// NewTimer returns a Timer that sends the current time on its channel after d.
+	NewTimer(d time.Duration) Timer
+	// AfterFunc waits for d to elapse and then calls f in its own goroutine.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a single event created by a Clock, like a *time.Timer.
+type Timer interface {
+	// C returns the channel the time is sent on. It is nil for timers created by AfterFunc.
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// realClock is the Clock backed by the time package, which is used by default.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+func (t realTimer) Reset(d time.Duration) bool {
+	return t.timer.Reset(d)
+}
@@ -0,0 +1,152 @@
+package pubsub
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock is a Clock whose time only moves when Advance is called.
+// Timers that fall due are fired by Advance itself, so their effects are visible when it returns.
+type manualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+func (c *manualClock) NewTimer(d time.Duration) Timer {
+	return c.add(d, make(chan time.Time, 1), nil)
+}
+
+func (c *manualClock) AfterFunc(d time.Duration, f func()) Timer {
+	return c.add(d, nil, f)
+}
+
+func (c *manualClock) add(d time.Duration, ch chan time.Time, f func()) *manualTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &manualTimer{clock: c, when: c.now.Add(d), c: ch, f: f, active: true}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the time forward by d and fires the timers that fell due, in order.
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	var due, pending []*manualTimer
+	for _, t := range c.timers {
+		if !t.active {
+			continue
+		}
+		if t.when.After(now) {
+			pending = append(pending, t)
+		} else {
+			t.active = false
+			due = append(due, t)
+		}
+	}
+	c.timers = pending
+	c.mu.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].when.Before(due[j].when) })
+	for _, t := range due {
+		if t.f != nil {
+			t.f()
+		} else {
+			t.c <- now
+		}
+	}
+}
+
+type manualTimer struct {
+	clock  *manualClock
+	when   time.Time
+	c      chan time.Time
+	f      func()
+	active bool
+}
+
+func (t *manualTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *manualTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.active
+	t.active = false
+	return active
+}
+
+func (t *manualTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.active
+	t.when = t.clock.now.Add(d)
+	if !active {
+		t.active = true
+		t.clock.timers = append(t.clock.timers, t)
+	}
+	return active
+}
+
+func TestClockPublishAfter(t *testing.T) {
+	clock := newManualClock()
+	ps := New(WithClock(clock))
+	var received []any
+	if err := ps.Subscribe("testTopic", func(args ...any) {
+		received = append(received, args[0])
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if _, err := ps.PublishAfter("testTopic", time.Second, "delayed"); err != nil {
+		t.Fatalf("PublishAfter returned an error: %s", err.Error())
+	}
+	clock.Advance(999 * time.Millisecond)
+	if len(received) != 0 {
+		t.Fatalf("expected no message before the delay elapsed, got %v", received)
+	}
+	clock.Advance(time.Millisecond)
+	if len(received) != 1 || received[0] != "delayed" {
+		t.Errorf("expected the delayed message once the delay elapsed, got %v", received)
+	}
+}
+
+func TestClockEventTime(t *testing.T) {
+	clock := newManualClock()
+	ps := New(WithClock(clock))
+	var event Event
+	cancel, err := ps.SubscribeEvents("testTopic", func(e Event) {
+		event = e
+	})
+	if err != nil {
+		t.Fatalf("SubscribeEvents returned an error: %s", err.Error())
+	}
+	defer cancel()
+
+	clock.Advance(time.Hour)
+	if _, err := ps.PublishEvent("testTopic", 1); err != nil {
+		t.Fatalf("PublishEvent returned an error: %s", err.Error())
+	}
+	if !event.Time.Equal(clock.Now()) {
+		t.Errorf("expected the event time %s, got %s", clock.Now(), event.Time)
+	}
+}
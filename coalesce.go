@@ -0,0 +1,82 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// SubscribeCoalesced adds a handler to the topic that is called with the latest message
+// once no new message has been published for window. A burst of messages closer together
+// than window is collapsed into a single call with the args of the last one.
+// The handler is called from a timer goroutine, but never concurrently with itself.
+// The returned cancel function removes the handler and discards a pending message.
+func (p *pubsub) SubscribeCoalesced(topic string, window time.Duration, handler func(lastArgs ...any)) (cancel func(), err error) {
+	if handler == nil {
+		return nil, ErrNilHandler
+	}
+	c := &coalescer{clock: p.opts.clock, window: window, handler: handler}
+	unsubscribe, err := p.subscribe(topic, &subscription{handler: c.receive})
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		unsubscribe()
+		c.stop()
+	}, nil
+}
+
+// coalescer delays each message by its window and drops it if a newer one arrives meanwhile.
+type coalescer struct {
+	clock   Clock
+	window  time.Duration
+	handler func(...any)
+	// callMu serializes the calls of handler.
+	callMu sync.Mutex
+
+	// mu guards the fields below.
+	mu    sync.Mutex
+	timer Timer
+	// args is the latest message, which is waiting to be delivered if pending is set.
+	args    []any
+	pending bool
+	stopped bool
+}
+
+func (c *coalescer) receive(args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return
+	}
+	c.args = args
+	c.pending = true
+	if c.timer == nil {
+		c.timer = c.clock.AfterFunc(c.window, c.fire)
+		return
+	}
+	// If the timer already fired, fire delivers these args and the reset timer finds nothing pending.
+	c.timer.Reset(c.window)
+}
+
+func (c *coalescer) fire() {
+	c.callMu.Lock()
+	defer c.callMu.Unlock()
+	c.mu.Lock()
+	args, pending := c.args, c.pending && !c.stopped
+	c.args = nil
+	c.pending = false
+	c.mu.Unlock()
+	if pending {
+		c.handler(args...)
+	}
+}
+
+func (c *coalescer) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+	c.args = nil
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+}
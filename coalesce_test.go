@@ -0,0 +1,64 @@
+package pubsub
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubscribeCoalesced(t *testing.T) {
+	ps := New()
+	var mu sync.Mutex
+	var received [][]any
+	cancel, err := ps.SubscribeCoalesced("testTopic", 20*time.Millisecond, func(lastArgs ...any) {
+		mu.Lock()
+		received = append(received, lastArgs)
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("SubscribeCoalesced returned an error: %s", err.Error())
+	}
+	defer cancel()
+
+	// Two bursts separated by more than the window.
+	for burst := 0; burst < 2; burst++ {
+		for i := 0; i < 10; i++ {
+			if err := ps.Publish("testTopic", burst, i); err != nil {
+				t.Fatalf("Publish returned an error: %s", err.Error())
+			}
+		}
+		time.Sleep(60 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := [][]any{{0, 9}, {1, 9}}
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("expected one call per burst with the last args %v, got %v", want, received)
+	}
+}
+
+func TestSubscribeCoalescedCancel(t *testing.T) {
+	ps := New()
+	called := make(chan struct{}, 1)
+	cancel, err := ps.SubscribeCoalesced("testTopic", 10*time.Millisecond, func(lastArgs ...any) {
+		called <- struct{}{}
+	})
+	if err != nil {
+		t.Fatalf("SubscribeCoalesced returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	cancel()
+
+	select {
+	case <-called:
+		t.Error("handler was called after cancel")
+	case <-time.After(30 * time.Millisecond):
+	}
+	if n := ps.Snapshot()["testTopic"]; n != 0 {
+		t.Errorf("expected the handler to be removed, got %d handlers", n)
+	}
+}
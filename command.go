@@ -0,0 +1,91 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidMessage is returned by Do when a message's Args don't match its Operation.
+var ErrInvalidMessage = errors.New("pubsub: invalid message")
+
+// Do performs the operation described by msg.
+// The Args a message must carry depend on its Operation:
+//
+//   - Subscribe, SubscribeOnce and SubscribeOnceEach: exactly one func(...any), the handler.
+//   - Publish and TryPublish: the arguments passed to the handlers, if any.
+//   - Unsubscribe, UnsubscribeAll, CloseTopic and Shutdown: none.
+//
+// Topic is ignored by UnsubscribeAll and Shutdown. An error wrapping ErrInvalidMessage
+// is returned for malformed messages.
+func (p *pubsub) Do(msg Message) error {
+	return do(p, msg)
+}
+
+// do performs the operation described by msg on ps, which is a PubSub or a namespace of one.
+func do(ps PubSub, msg Message) error {
+	switch msg.Operation {
+	case Subscribe, SubscribeOnce, SubscribeOnceEach:
+		if len(msg.Args) != 1 {
+			return fmt.Errorf("%w: operation %s expects 1 handler argument, got %d", ErrInvalidMessage, msg.Operation, len(msg.Args))
+		}
+		handler, ok := msg.Args[0].(func(...any))
+		if !ok {
+			return fmt.Errorf("%w: operation %s expects a func(...any) argument, got %T", ErrInvalidMessage, msg.Operation, msg.Args[0])
+		}
+		switch msg.Operation {
+		case SubscribeOnce:
+			_, err := ps.SubscribeOnce(msg.Topic, handler)
+			return err
+		case SubscribeOnceEach:
+			_, err := ps.SubscribeOnceEach(msg.Topic, handler)
+			return err
+		default:
+			return ps.Subscribe(msg.Topic, handler)
+		}
+	case Publish:
+		return ps.Publish(msg.Topic, msg.Args...)
+	case TryPublish:
+		return ps.TryPublish(msg.Topic, msg.Args...)
+	case Unsubscribe, UnsubscribeAll, CloseTopic, Shutdown:
+		if len(msg.Args) != 0 {
+			return fmt.Errorf("%w: operation %s expects no arguments, got %d", ErrInvalidMessage, msg.Operation, len(msg.Args))
+		}
+		switch msg.Operation {
+		case Unsubscribe:
+			return ps.Unsubscribe(msg.Topic)
+		case UnsubscribeAll:
+			return ps.UnsubscribeAll()
+		case CloseTopic:
+			return ps.CloseTopic(msg.Topic)
+		default:
+			return ps.Shutdown()
+		}
+	default:
+		return fmt.Errorf("%w: unknown operation %s", ErrInvalidMessage, msg.Operation)
+	}
+}
+
+// Run performs the operations of the messages received from commands with Do, one at a
+// time, until commands is closed or ctx is done. It returns nil when commands is closed,
+// the context's error when ctx is done, and stops at the first message that fails.
+func (p *pubsub) Run(ctx context.Context, commands <-chan Message) error {
+	return run(ctx, p, commands)
+}
+
+// run performs the operations of the messages received from commands on ps, like Run.
+func run(ctx context.Context, ps PubSub, commands <-chan Message) error {
+	for {
+		select {
+		case msg, ok := <-commands:
+			if !ok {
+				return nil
+			}
+			if err := ps.Do(msg); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
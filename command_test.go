@@ -0,0 +1,140 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDo(t *testing.T) {
+	ps := New()
+	topic := "testTopic"
+	calls := 0
+	handler := func(args ...any) {
+		calls++
+	}
+
+	do := func(msg Message) {
+		t.Helper()
+		if err := ps.Do(msg); err != nil {
+			t.Fatalf("Do returned an error for operation %d: %s", msg.Operation, err.Error())
+		}
+	}
+
+	do(Message{Topic: topic, Operation: Subscribe, Args: []any{handler}})
+	do(Message{Topic: topic, Operation: Publish, Args: []any{"test message"}})
+	do(Message{Topic: topic, Operation: TryPublish, Args: []any{"test message"}})
+	if calls != 2 {
+		t.Errorf("expected 2 calls after Subscribe and publishes, got %d", calls)
+	}
+
+	do(Message{Topic: topic, Operation: Unsubscribe})
+	do(Message{Topic: topic, Operation: Publish})
+	if calls != 2 {
+		t.Errorf("expected no calls after Unsubscribe, got %d", calls-2)
+	}
+
+	calls = 0
+	do(Message{Topic: "once", Operation: SubscribeOnce, Args: []any{handler}})
+	do(Message{Topic: "once", Operation: Publish})
+	do(Message{Topic: "once", Operation: Publish})
+	if calls != 1 {
+		t.Errorf("expected 1 call for SubscribeOnce, got %d", calls)
+	}
+
+	calls = 0
+	do(Message{Topic: "onceEach", Operation: SubscribeOnceEach, Args: []any{handler}})
+	do(Message{Topic: "onceEach", Operation: Publish})
+	do(Message{Topic: "onceEach", Operation: Publish})
+	if calls != 1 {
+		t.Errorf("expected 1 call for SubscribeOnceEach, got %d", calls)
+	}
+
+	calls = 0
+	do(Message{Topic: "closed", Operation: Subscribe, Args: []any{handler}})
+	do(Message{Topic: "closed", Operation: CloseTopic})
+	do(Message{Topic: "closed", Operation: Publish})
+	if calls != 0 {
+		t.Errorf("expected no calls after CloseTopic, got %d", calls)
+	}
+
+	do(Message{Topic: "all", Operation: Subscribe, Args: []any{handler}})
+	do(Message{Operation: UnsubscribeAll})
+	do(Message{Topic: "all", Operation: Publish})
+	if calls != 0 {
+		t.Errorf("expected no calls after UnsubscribeAll, got %d", calls)
+	}
+
+	do(Message{Topic: "shutdown", Operation: Subscribe, Args: []any{handler}})
+	do(Message{Operation: Shutdown})
+	if err := ps.Do(Message{Topic: "shutdown", Operation: Publish}); !errors.Is(err, ErrShutdown) {
+		t.Errorf("expected ErrShutdown publishing after Shutdown, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("expected no calls after Shutdown, got %d", calls)
+	}
+}
+
+func TestDoInvalidMessage(t *testing.T) {
+	ps := New()
+
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{"subscribe without handler", Message{Topic: "t", Operation: Subscribe}},
+		{"subscribe with non-handler", Message{Topic: "t", Operation: Subscribe, Args: []any{"nope"}}},
+		{"subscribe with extra args", Message{Topic: "t", Operation: SubscribeOnce, Args: []any{func(...any) {}, 1}}},
+		{"unsubscribe with args", Message{Topic: "t", Operation: Unsubscribe, Args: []any{1}}},
+		{"unknown operation", Message{Topic: "t", Operation: Operation(42)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ps.Do(tt.msg); !errors.Is(err, ErrInvalidMessage) {
+				t.Errorf("expected ErrInvalidMessage, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	ps := New()
+	var received []any
+	handler := func(args ...any) {
+		received = append(received, args[0])
+	}
+
+	commands := make(chan Message)
+	done := make(chan error, 1)
+	go func() {
+		done <- ps.Run(context.Background(), commands)
+	}()
+	commands <- Message{Topic: "testTopic", Operation: Subscribe, Args: []any{handler}}
+	commands <- Message{Topic: "testTopic", Operation: Publish, Args: []any{"first"}}
+	commands <- Message{Topic: "testTopic", Operation: Unsubscribe}
+	commands <- Message{Topic: "testTopic", Operation: Publish, Args: []any{"second"}}
+	close(commands)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned an error: %s", err.Error())
+	}
+	if len(received) != 1 || received[0] != "first" {
+		t.Errorf("expected only the message published before Unsubscribe, got %v", received)
+	}
+}
+
+func TestRunStops(t *testing.T) {
+	ps := New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ps.Run(ctx, make(chan Message)); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	commands := make(chan Message, 1)
+	commands <- Message{Topic: "testTopic", Operation: Subscribe}
+	if err := ps.Run(context.Background(), commands); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("expected ErrInvalidMessage, got %v", err)
+	}
+}
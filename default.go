@@ -0,0 +1,73 @@
+package pubsub
+
+// defaultHandler is a handler added with SubscribeDefault.
+type defaultHandler struct {
+	fn func(topic string, args ...any)
+}
+
+// SubscribeDefault adds a catch-all handler that is called with the messages published to
+// topics without handlers, and the name of the topic, which helps logging unexpected
+// messages or finding misspelled topic names. It isn't called for a message that reached
+// a handler, even one whose filter didn't match it, nor for messages published to closed
+// or paused topics, nor for those the PubSub publishes to itself, such as to the system,
+// dead-letter or error topic. A panic of the handler is recovered and reported like that
+// of a topic's handler, but the panic policy doesn't apply to it. On an asynchronous
+// PubSub it is called when the message is delivered.
+// The returned cancel function removes the handler.
+func (p *pubsub) SubscribeDefault(handler func(topic string, args ...any)) (cancel func(), err error) {
+	if handler == nil {
+		return nil, ErrNilHandler
+	}
+	if p.shutdown.Load() {
+		return nil, ErrShutdown
+	}
+	d := &defaultHandler{fn: handler}
+	p.defaultsMu.Lock()
+	p.defaults = append(append([]*defaultHandler(nil), p.defaults...), d)
+	p.defaultsMu.Unlock()
+	return func() {
+		p.defaultsMu.Lock()
+		defer p.defaultsMu.Unlock()
+		for i, other := range p.defaults {
+			if other == d {
+				p.defaults = append(append([]*defaultHandler(nil), p.defaults[:i]...), p.defaults[i+1:]...)
+				return
+			}
+		}
+	}, nil
+}
+
+// hasDefaults reports whether p has default handlers.
+func (p *pubsub) hasDefaults() bool {
+	p.defaultsMu.RLock()
+	defer p.defaultsMu.RUnlock()
+	return len(p.defaults) > 0
+}
+
+// callDefaults calls the default handlers of p with a message published to the topic,
+// which has no handlers. Messages the PubSub publishes to itself, such as events of the
+// system topic nobody listens to or late replies to requests, are not passed on.
+func (p *pubsub) callDefaults(topic string, args []any) {
+	p.defaultsMu.RLock()
+	defaults := p.defaults
+	p.defaultsMu.RUnlock()
+	if len(defaults) == 0 || p.internal(topic) {
+		return
+	}
+	for i, d := range defaults {
+		p.callDefault(i, d, topic, args)
+	}
+}
+
+// callDefault calls the default handler d, the one at index, recovering its panic like
+// that of a topic's handler.
+func (p *pubsub) callDefault(index int, d *defaultHandler, topic string, args []any) {
+	if p.recovers() {
+		defer func() {
+			if r := recover(); r != nil {
+				p.defaultPanicked(index, topic, args, r)
+			}
+		}()
+	}
+	d.fn(topic, args...)
+}
@@ -0,0 +1,158 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSubscribeDefault(t *testing.T) {
+	ps := New()
+	type message struct {
+		topic string
+		args  []any
+	}
+	var unmatched []message
+	cancel, err := ps.SubscribeDefault(func(topic string, args ...any) {
+		unmatched = append(unmatched, message{topic, args})
+	})
+	if err != nil {
+		t.Fatalf("SubscribeDefault returned an error: %s", err.Error())
+	}
+	var received []any
+	if err := ps.Subscribe("orders", func(args ...any) { received = append(received, args...) }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("orders", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("oders", 2); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	// A topic whose handlers were all removed has no handlers either.
+	cancelInvoices, err := ps.SubscribeOnce("invoices", func(args ...any) {})
+	if err != nil {
+		t.Fatalf("SubscribeOnce returned an error: %s", err.Error())
+	}
+	cancelInvoices()
+	if err := ps.Publish("invoices", 3); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+
+	if want := []any{1}; !reflect.DeepEqual(received, want) {
+		t.Errorf("expected the handler to receive %v, got %v", want, received)
+	}
+	want := []message{{"oders", []any{2}}, {"invoices", []any{3}}}
+	if !reflect.DeepEqual(unmatched, want) {
+		t.Errorf("expected the default handler to receive %v, got %v", want, unmatched)
+	}
+
+	cancel()
+	if err := ps.Publish("oders", 4); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(unmatched) != 2 {
+		t.Errorf("expected no call after cancel, got %v", unmatched)
+	}
+}
+
+func TestSubscribeDefaultFiltered(t *testing.T) {
+	ps := New()
+	calls := 0
+	if _, err := ps.SubscribeDefault(func(topic string, args ...any) { calls++ }); err != nil {
+		t.Fatalf("SubscribeDefault returned an error: %s", err.Error())
+	}
+	if _, err := ps.SubscribeFiltered("orders", func(args ...any) bool { return false }, func(args ...any) {}); err != nil {
+		t.Fatalf("SubscribeFiltered returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("orders", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if calls != 0 {
+		t.Errorf("expected the default handler not to be called for a topic with handlers, got %d calls", calls)
+	}
+}
+
+func TestSubscribeDefaultAsync(t *testing.T) {
+	ps := New(WithAsync(4))
+	defer ps.Shutdown()
+	topics := make(chan string, 1)
+	if _, err := ps.SubscribeDefault(func(topic string, args ...any) { topics <- topic }); err != nil {
+		t.Fatalf("SubscribeDefault returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("nobody", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	select {
+	case topic := <-topics:
+		if topic != "nobody" {
+			t.Errorf("expected topic nobody, got %q", topic)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the default handler was not called")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ps.Drain(ctx); err != nil {
+		t.Fatalf("Drain returned an error: %s", err.Error())
+	}
+}
+
+func TestSubscribeDefaultErrors(t *testing.T) {
+	ps := New()
+	if _, err := ps.SubscribeDefault(nil); !errors.Is(err, ErrNilHandler) {
+		t.Fatalf("expected ErrNilHandler, got %v", err)
+	}
+	if err := ps.Shutdown(); err != nil {
+		t.Fatalf("Shutdown returned an error: %s", err.Error())
+	}
+	if _, err := ps.SubscribeDefault(func(string, ...any) {}); !errors.Is(err, ErrShutdown) {
+		t.Fatalf("expected ErrShutdown, got %v", err)
+	}
+}
+
+func TestSubscribeDefaultInternalTopics(t *testing.T) {
+	ps := New(WithSystemTopic(), WithDeadLetter("dead"), WithErrorTopic("errors"))
+	var topics []string
+	if _, err := ps.SubscribeDefault(func(topic string, args ...any) {
+		topics = append(topics, topic)
+	}); err != nil {
+		t.Fatalf("SubscribeDefault returned an error: %s", err.Error())
+	}
+
+	// Creating the topic publishes to the system topic, and the panic to the dead-letter
+	// and error topics, none of which have handlers.
+	if err := ps.Subscribe("testTopic", func(args ...any) { panic("boom") }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if err := ps.Publish(inboxPrefix+"1", "late reply"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(topics) != 0 {
+		t.Errorf("expected no internal topic to reach the default handler, got %v", topics)
+	}
+}
+
+func TestSubscribeDefaultPanic(t *testing.T) {
+	ps := New(WithDeadLetter("dead"))
+	var dead []any
+	if err := ps.Subscribe("dead", func(args ...any) { dead = args }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if _, err := ps.SubscribeDefault(func(topic string, args ...any) { panic("boom") }); err != nil {
+		t.Fatalf("SubscribeDefault returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("testTopic", "message"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(dead) != 2 || dead[0] != "message" || !errors.Is(dead[1].(error), ErrHandlerPanic) {
+		t.Errorf("expected the message to be dead-lettered with ErrHandlerPanic, got %v", dead)
+	}
+}
@@ -0,0 +1,165 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// dispatcher delivers all publishes of a PubSub one at a time, in the order they were
+// made, from a single goroutine. See WithGlobalOrder.
+type dispatcher struct {
+	p    *pubsub
+	wait bool
+	// wake is signalled when a message is enqueued or the dispatcher is stopped.
+	wake chan struct{}
+
+	mu    sync.Mutex
+	queue []dispatchItem
+	// fair, if not nil, holds the pending items instead of queue. See WithFairScheduling.
+	fair    *fairQueue
+	stopped bool
+}
+
+type dispatchItem struct {
+	msg Message
+	// barrier, if not nil, is closed when the item is reached instead of delivering msg.
+	barrier chan struct{}
+	// result receives the delivery error if the publisher waits for completion.
+	result chan error
+	// ack, if not nil, is done once msg has been delivered. See PublishAck.
+	ack *sync.WaitGroup
+	// ifSubscribed delivers msg only if its topic has handlers. See PublishIfSubscribed.
+	ifSubscribed bool
+	// seq is the position of the item in a fairQueue.
+	seq uint64
+}
+
+func newDispatcher(p *pubsub, wait bool) *dispatcher {
+	d := &dispatcher{
+		p:    p,
+		wait: wait,
+		wake: make(chan struct{}, 1),
+	}
+	if p.opts.fairScheduling {
+		d.fair = &fairQueue{}
+	}
+	go d.run()
+	return d
+}
+
+// enqueue adds msg to the queue and, if the dispatcher waits for completion,
+// blocks until it has been delivered. Messages enqueued after stop are dropped.
+func (d *dispatcher) enqueue(msg Message, ifSubscribed bool, ack *sync.WaitGroup) error {
+	item := dispatchItem{msg: msg, ack: ack, ifSubscribed: ifSubscribed}
+	if d.wait {
+		item.result = make(chan error, 1)
+	}
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return nil
+	}
+	if ack != nil {
+		// Hold the ack until the message has been handed over to its topic, which adds
+		// to it again if the topic queues the message.
+		ack.Add(1)
+	}
+	d.push(item)
+	d.mu.Unlock()
+	d.signal()
+	if item.result == nil {
+		return nil
+	}
+	return <-item.result
+}
+
+func (d *dispatcher) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *dispatcher) run() {
+	for {
+		d.mu.Lock()
+		for d.len() == 0 {
+			if d.stopped {
+				d.mu.Unlock()
+				return
+			}
+			d.mu.Unlock()
+			<-d.wake
+			d.mu.Lock()
+		}
+		item := d.pop()
+		d.mu.Unlock()
+
+		if item.barrier != nil {
+			close(item.barrier)
+			continue
+		}
+		err := d.p.deliver(context.Background(), item.msg.Topic, item.msg.Args, item.msg.Operation == TryPublish, item.ifSubscribed, item.ack)
+		if item.ack != nil {
+			item.ack.Done()
+		}
+		if item.result != nil {
+			item.result <- err
+		}
+	}
+}
+
+// push adds item to the pending items. It must be called with mu held.
+func (d *dispatcher) push(item dispatchItem) {
+	if d.fair != nil {
+		d.fair.push(item)
+		return
+	}
+	d.queue = append(d.queue, item)
+}
+
+// pop removes and returns the next pending item. It must be called with mu held.
+func (d *dispatcher) pop() dispatchItem {
+	if d.fair != nil {
+		return d.fair.pop()
+	}
+	item := d.queue[0]
+	d.queue[0] = dispatchItem{}
+	d.queue = d.queue[1:]
+	return item
+}
+
+// len returns the number of pending items. It must be called with mu held.
+func (d *dispatcher) len() int {
+	if d.fair != nil {
+		return d.fair.len()
+	}
+	return len(d.queue)
+}
+
+// stop makes the dispatcher exit once the messages already enqueued are delivered.
+func (d *dispatcher) stop() {
+	d.mu.Lock()
+	d.stopped = true
+	d.mu.Unlock()
+	d.signal()
+}
+
+// flush waits until the messages enqueued before it have been delivered, or ctx is done.
+func (d *dispatcher) flush(ctx context.Context) error {
+	barrier := make(chan struct{})
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return nil
+	}
+	d.push(dispatchItem{barrier: barrier})
+	d.mu.Unlock()
+	d.signal()
+	select {
+	case <-barrier:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,90 @@
+package pubsub
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGlobalOrder(t *testing.T) {
+	ps := New(WithGlobalOrder(false))
+	topics := []string{"a", "b", "c"}
+	const total = 300
+
+	var delivered []int
+	var wg sync.WaitGroup
+	wg.Add(total)
+	for _, topic := range topics {
+		err := ps.Subscribe(topic, func(args ...any) {
+			delivered = append(delivered, args[0].(int))
+			wg.Done()
+		})
+		if err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	for i := 0; i < total; i++ {
+		if err := ps.Publish(topics[i%len(topics)], i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	wg.Wait()
+
+	for i, n := range delivered {
+		if n != i {
+			t.Fatalf("expected message %d at position %d, got %d", i, i, n)
+		}
+	}
+}
+
+func TestGlobalOrderConcurrentPublishers(t *testing.T) {
+	ps := New(WithGlobalOrder(true))
+	const publishers, perPublisher = 4, 100
+
+	var running atomic.Int32
+	var mu sync.Mutex
+	delivered := make(map[string][]int)
+	for p := 0; p < publishers; p++ {
+		topic := "topic" + strconv.Itoa(p)
+		err := ps.Subscribe(topic, func(args ...any) {
+			if running.Add(1) != 1 {
+				t.Error("handlers ran concurrently")
+			}
+			mu.Lock()
+			delivered[topic] = append(delivered[topic], args[0].(int))
+			mu.Unlock()
+			running.Add(-1)
+		})
+		if err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	var wg sync.WaitGroup
+	for p := 0; p < publishers; p++ {
+		wg.Add(1)
+		go func(topic string) {
+			defer wg.Done()
+			for i := 0; i < perPublisher; i++ {
+				if err := ps.Publish(topic, i); err != nil {
+					t.Errorf("Publish returned an error: %s", err.Error())
+				}
+			}
+		}("topic" + strconv.Itoa(p))
+	}
+	// Publish waits for delivery, so everything is delivered once the publishers return.
+	wg.Wait()
+
+	for topic, ns := range delivered {
+		if len(ns) != perPublisher {
+			t.Fatalf("expected %d messages on %s, got %d", perPublisher, topic, len(ns))
+		}
+		for i, n := range ns {
+			if n != i {
+				t.Fatalf("%s: expected message %d at position %d, got %d", topic, i, i, n)
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package pubsub
+
+import "context"
+
+// Drain waits until the messages queued so far have been delivered and the handlers
+// running have returned, then returns while the topics stay open and keep their handlers.
+// That includes the messages the handlers publish meanwhile. With WithAsync it flushes
+// the topics' queues, and with WithGlobalOrder the dispatcher's queue. It returns the
+// context's error if ctx is done first. Drain must not be called from a handler, as it
+// would wait for itself.
+func (p *pubsub) Drain(ctx context.Context) error {
+	return p.drain(ctx, p.allTopics)
+}
+
+// drain waits until the messages of the topics returned by topics have been delivered.
+func (p *pubsub) drain(ctx context.Context, topics func() []*topic) error {
+	for {
+		if p.dispatcher != nil {
+			if err := p.dispatcher.flush(ctx); err != nil {
+				return err
+			}
+		}
+		waited := false
+		for _, t := range topics() {
+			w, err := t.drain(ctx)
+			if err != nil {
+				return err
+			}
+			waited = waited || w
+		}
+		// Handlers may have published to topics that were already drained.
+		if !waited {
+			return nil
+		}
+	}
+}
+
+// drain waits until no message of the topic is pending or being delivered, and reports
+// whether it had to wait.
+func (t *topic) drain(ctx context.Context) (waited bool, err error) {
+	for {
+		t.mu.Lock()
+		if t.closed || t.pending.Load() == 0 && t.delivering == 0 {
+			t.mu.Unlock()
+			return waited, nil
+		}
+		if t.drained == nil {
+			t.drained = make(chan struct{})
+		}
+		drained := t.drained
+		t.mu.Unlock()
+
+		waited = true
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			return waited, ctx.Err()
+		}
+	}
+}
+
+// unqueue records that n pending messages were delivered or discarded.
+func (t *topic) unqueue(n int) {
+	if t.pending.Add(-int64(n)) == 0 {
+		t.mu.Lock()
+		t.notifyDrained()
+		t.mu.Unlock()
+	}
+}
+
+// notifyDrained wakes up the Drain calls waiting for the topic if nothing is pending or
+// being delivered anymore. It must be called with mu held.
+func (t *topic) notifyDrained() {
+	if t.drained != nil && t.delivering == 0 && t.pending.Load() == 0 {
+		close(t.drained)
+		t.drained = nil
+	}
+}
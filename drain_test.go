@@ -0,0 +1,77 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	ps := New(WithAsync(16))
+	defer ps.Shutdown()
+
+	var first, second atomic.Int64
+	if err := ps.Subscribe("first", func(args ...any) {
+		time.Sleep(time.Millisecond)
+		first.Add(1)
+		// Messages published by handlers while draining are waited for too.
+		if err := ps.Publish("second"); err != nil {
+			t.Errorf("Publish returned an error: %s", err.Error())
+		}
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("second", func(args ...any) {
+		time.Sleep(time.Millisecond)
+		second.Add(1)
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := ps.Publish("first", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ps.Drain(ctx); err != nil {
+		t.Fatalf("Drain returned an error: %s", err.Error())
+	}
+	if n, m := first.Load(), second.Load(); n != 10 || m != 10 {
+		t.Errorf("expected all 10 messages of each topic to be handled, got %d and %d", n, m)
+	}
+
+	// The bus is still usable after draining.
+	if err := ps.Publish("second"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if err := ps.Drain(ctx); err != nil {
+		t.Fatalf("Drain returned an error: %s", err.Error())
+	}
+	if n := second.Load(); n != 11 {
+		t.Errorf("expected the message published after Drain to be handled, got %d messages", n)
+	}
+}
+
+func TestDrainCancelled(t *testing.T) {
+	ps := New(WithAsync(1))
+	release := make(chan struct{})
+	if err := ps.Subscribe("testTopic", func(args ...any) {
+		<-release
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := ps.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	close(release)
+}
@@ -0,0 +1,88 @@
+package pubsub
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Event is a message published with PublishEvent or PublishWithID, wrapped with metadata
+// about the publish.
+type Event struct {
+	// ID is unique per published event.
+	ID      string
+	Topic   string
+	Time    time.Time
+	Payload any
+	// CorrelationID is the ID passed to PublishWithID, which ties the event to the
+	// operation that caused it. It is empty for events published with PublishEvent.
+	CorrelationID string
+	// Seq numbers the events published to the topic, starting at 1, so handlers can detect
+	// gaps and reordering. Events published from one goroutine have increasing Seqs. It
+	// only starts over when the topic is closed, and is 0 if the topic didn't exist when
+	// the event was published, in which case it has no handlers to receive it.
+	Seq uint64
+}
+
+// eventID generates the IDs of published events.
+var eventID atomic.Uint64
+
+// PublishEvent publishes payload to the topic wrapped in an Event and returns the event's ID.
+// Handlers added with SubscribeEvents receive the Event; other handlers receive it as their
+// only argument.
+func (p *pubsub) PublishEvent(topic string, payload any) (string, error) {
+	event := p.newEvent(topic, payload)
+	if err := p.Publish(topic, event); err != nil {
+		return "", err
+	}
+	return event.ID, nil
+}
+
+// PublishWithID publishes args to the topic wrapped in an Event carrying correlationID,
+// so handlers can tie their side effects to the publish, for example in logs. The Event's
+// Payload is args. Handlers added with SubscribeEvents receive the Event; other handlers
+// receive it as their only argument. On an asynchronous PubSub the ID is queued with the
+// message.
+func (p *pubsub) PublishWithID(topic, correlationID string, args ...any) error {
+	event := p.newEvent(topic, append([]any(nil), args...))
+	event.CorrelationID = correlationID
+	return p.Publish(topic, event)
+}
+
+// newEvent returns an Event with a new ID and the topic's next sequence number for
+// payload published now to the topic.
+func (p *pubsub) newEvent(topic string, payload any) Event {
+	event := Event{
+		ID:      strconv.FormatUint(eventID.Add(1), 10),
+		Topic:   topic,
+		Time:    p.opts.clock.Now(),
+		Payload: payload,
+	}
+	if name, err := p.topicName(topic); err == nil {
+		if t, ok := p.getTopic(name); ok {
+			event.Seq = t.seq.Add(1)
+		}
+	}
+	return event
+}
+
+// SubscribeEvents adds a handler to the topic that receives the events published with
+// PublishEvent. Messages published with the raw API are skipped. The returned cancel
+// function removes the handler from the topic.
+func (p *pubsub) SubscribeEvents(topic string, handler func(Event)) (cancel func(), err error) {
+	if handler == nil {
+		return nil, ErrNilHandler
+	}
+	return p.subscribe(topic, &subscription{
+		handler: func(args ...any) {
+			handler(args[0].(Event))
+		},
+		filter: func(args ...any) bool {
+			if len(args) != 1 {
+				return false
+			}
+			_, ok := args[0].(Event)
+			return ok
+		},
+	})
+}
@@ -0,0 +1,205 @@
+package pubsub
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPublishEvent(t *testing.T) {
+	ps := New()
+	var events []Event
+	cancel, err := ps.SubscribeEvents("testTopic", func(event Event) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatalf("SubscribeEvents returned an error: %s", err.Error())
+	}
+	defer cancel()
+
+	// Raw publishes are skipped.
+	if err := ps.Publish("testTopic", "raw"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	start := time.Now()
+	firstID, err := ps.PublishEvent("testTopic", "first")
+	if err != nil {
+		t.Fatalf("PublishEvent returned an error: %s", err.Error())
+	}
+	secondID, err := ps.PublishEvent("testTopic", "second")
+	if err != nil {
+		t.Fatalf("PublishEvent returned an error: %s", err.Error())
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if firstID == "" || firstID == secondID {
+		t.Errorf("expected unique non-empty IDs, got %q and %q", firstID, secondID)
+	}
+	for i, id := range []string{firstID, secondID} {
+		event := events[i]
+		if event.ID != id {
+			t.Errorf("expected event ID %q, got %q", id, event.ID)
+		}
+		if event.Topic != "testTopic" {
+			t.Errorf("expected topic testTopic, got %q", event.Topic)
+		}
+		if event.Time.Before(start) || time.Since(event.Time) > time.Second {
+			t.Errorf("expected a recent timestamp, got %s", event.Time)
+		}
+	}
+	if events[0].Payload != "first" || events[1].Payload != "second" {
+		t.Errorf("expected payloads first and second, got %v and %v", events[0].Payload, events[1].Payload)
+	}
+}
+
+func TestPublishEventRawSubscriber(t *testing.T) {
+	ps := New()
+	var received []any
+	if err := ps.Subscribe("testTopic", func(args ...any) {
+		received = args
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	id, err := ps.PublishEvent("testTopic", 1)
+	if err != nil {
+		t.Fatalf("PublishEvent returned an error: %s", err.Error())
+	}
+	if len(received) != 1 {
+		t.Fatalf("expected the event as the only argument, got %v", received)
+	}
+	if event, ok := received[0].(Event); !ok || event.ID != id || event.Payload != 1 {
+		t.Errorf("expected event %s with payload 1, got %v", id, received[0])
+	}
+}
+
+func TestPublishWithID(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"sync":  nil,
+		"async": {WithAsync(8)},
+	} {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			ps := New(opts...)
+			defer ps.Shutdown()
+			events := make(chan Event, 1)
+			_, err := ps.SubscribeEvents("testTopic", func(event Event) {
+				events <- event
+			})
+			if err != nil {
+				t.Fatalf("SubscribeEvents returned an error: %s", err.Error())
+			}
+
+			if err := ps.PublishWithID("testTopic", "trace-1", "payload", 2); err != nil {
+				t.Fatalf("PublishWithID returned an error: %s", err.Error())
+			}
+
+			select {
+			case event := <-events:
+				if event.CorrelationID != "trace-1" {
+					t.Errorf("expected correlation ID trace-1, got %q", event.CorrelationID)
+				}
+				if args, ok := event.Payload.([]any); !ok || len(args) != 2 || args[0] != "payload" || args[1] != 2 {
+					t.Errorf("expected the args as payload, got %v", event.Payload)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("event was not delivered")
+			}
+		})
+	}
+}
+
+func TestEventSeq(t *testing.T) {
+	ps := New()
+	var seqs []uint64
+	subscribe := func() func() {
+		cancel, err := ps.SubscribeEvents("orders", func(event Event) {
+			seqs = append(seqs, event.Seq)
+		})
+		if err != nil {
+			t.Fatalf("SubscribeEvents returned an error: %s", err.Error())
+		}
+		return cancel
+	}
+	cancel := subscribe()
+	if _, err := ps.SubscribeEvents("invoices", func(Event) {}); err != nil {
+		t.Fatalf("SubscribeEvents returned an error: %s", err.Error())
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := ps.PublishEvent("orders", i); err != nil {
+			t.Fatalf("PublishEvent returned an error: %s", err.Error())
+		}
+		// Other topics and raw publishes don't use up sequence numbers.
+		if _, err := ps.PublishEvent("invoices", i); err != nil {
+			t.Fatalf("PublishEvent returned an error: %s", err.Error())
+		}
+		if err := ps.Publish("orders", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if err := ps.PublishWithID("orders", "req-1", 3); err != nil {
+		t.Fatalf("PublishWithID returned an error: %s", err.Error())
+	}
+
+	// Removing the handler doesn't reset the sequence.
+	cancel()
+	subscribe()
+	if _, err := ps.PublishEvent("orders", 4); err != nil {
+		t.Fatalf("PublishEvent returned an error: %s", err.Error())
+	}
+
+	if want := []uint64{1, 2, 3, 4, 5}; !reflect.DeepEqual(seqs, want) {
+		t.Fatalf("expected sequence numbers %v, got %v", want, seqs)
+	}
+
+	// Neither does Unsubscribe, although it closes the topic.
+	if err := ps.Unsubscribe("orders"); err != nil {
+		t.Fatalf("Unsubscribe returned an error: %s", err.Error())
+	}
+	if topic, _ := ps.(*pubsub).getTopic("orders"); topic.seq.Load() != 5 {
+		t.Fatalf("expected Unsubscribe to keep the sequence, got %d", topic.seq.Load())
+	}
+	if err := ps.CloseTopic("orders"); err != nil {
+		t.Fatalf("CloseTopic returned an error: %s", err.Error())
+	}
+	if topic, _ := ps.(*pubsub).getTopic("orders"); topic.seq.Load() != 0 {
+		t.Fatalf("expected CloseTopic to reset the sequence, got %d", topic.seq.Load())
+	}
+}
+
+func TestEventSeqConcurrent(t *testing.T) {
+	ps := New()
+	var mu sync.Mutex
+	seen := make(map[uint64]bool)
+	if _, err := ps.SubscribeEvents("orders", func(event Event) {
+		mu.Lock()
+		seen[event.Seq] = true
+		mu.Unlock()
+	}); err != nil {
+		t.Fatalf("SubscribeEvents returned an error: %s", err.Error())
+	}
+
+	const publishers, perPublisher = 4, 100
+	var wg sync.WaitGroup
+	for i := 0; i < publishers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perPublisher; j++ {
+				_, _ = ps.PublishEvent("orders", j)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every number is used exactly once, without gaps.
+	for seq := uint64(1); seq <= publishers*perPublisher; seq++ {
+		if !seen[seq] {
+			t.Fatalf("sequence number %d is missing", seq)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownTopic is returned when subscribing or publishing to a topic that wasn't
+// declared with DeclareTopic, if the PubSub was created with WithExplicitTopics.
+var ErrUnknownTopic = errors.New("pubsub: unknown topic")
+
+// DeclareTopic declares the topic, which creates it. With WithExplicitTopics, topics must
+// be declared before they can be subscribed or published to; otherwise declaring is
+// optional. Declaring a topic twice is a no-op. It returns ErrShutdown after Shutdown.
+func (p *pubsub) DeclareTopic(topic string) error {
+	topic, err := p.topicName(topic)
+	if err != nil {
+		return err
+	}
+	if p.shutdown.Load() {
+		return ErrShutdown
+	}
+	p.declaredMu.Lock()
+	if p.declared == nil {
+		p.declared = make(map[string]bool)
+	}
+	p.declared[topic] = true
+	p.declaredMu.Unlock()
+	p.getOrCreateTopic(topic)
+	return nil
+}
+
+// ExportTopics returns the names of the open and declared topics in sorted order, which
+// ImportTopics recreates, for example after a restart. Handlers aren't exported, and
+// neither are the topics the PubSub publishes to itself.
+func (p *pubsub) ExportTopics() []string {
+	names := p.Topics()
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		seen[name] = true
+	}
+	p.declaredMu.RLock()
+	for name := range p.declared {
+		if !seen[name] && !p.internal(name) {
+			names = append(names, name)
+		}
+	}
+	p.declaredMu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// ImportTopics declares each of the topics with DeclareTopic, which creates it empty and
+// open, so subscribers can attach to them right away even with WithExplicitTopics.
+// It declares every valid topic and returns the errors of the others, joined.
+func (p *pubsub) ImportTopics(topics []string) error {
+	var errs []error
+	for _, topic := range topics {
+		if err := p.DeclareTopic(topic); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// known returns ErrShutdown if the PubSub has been shut down, and ErrUnknownTopic if
+// topics must be declared and the topic wasn't. The topics the PubSub publishes to
+// itself, such as the dead-letter topic, the error topic, the system topic and request
+// inboxes, are always known, as are the request topics of known topics.
+func (p *pubsub) known(topic string) error {
+	if p.shutdown.Load() {
+		return ErrShutdown
+	}
+	if !p.opts.explicitTopics {
+		return nil
+	}
+	if p.internal(topic) {
+		return nil
+	}
+	p.declaredMu.RLock()
+	declared := p.declared[topic]
+	p.declaredMu.RUnlock()
+	if !declared {
+		return fmt.Errorf("%w: %q", ErrUnknownTopic, topic)
+	}
+	return nil
+}
+
+// internal reports whether the PubSub publishes to the topic itself: the dead-letter
+// topic, the error topic, the system topic, request topics and request inboxes.
+func (p *pubsub) internal(topic string) bool {
+	return topic == p.opts.deadLetterTopic || topic == p.opts.errorTopic || topic == SystemTopic ||
+		strings.HasPrefix(topic, inboxPrefix) || strings.HasPrefix(topic, requestPrefix)
+}
@@ -0,0 +1,119 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExplicitTopicsDeclared(t *testing.T) {
+	ps := New(WithExplicitTopics())
+	if err := ps.DeclareTopic("testTopic"); err != nil {
+		t.Fatalf("DeclareTopic returned an error: %s", err.Error())
+	}
+	called := false
+	err := ps.Subscribe("testTopic", func(args ...any) {
+		called = true
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("testTopic"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+
+	if !called {
+		t.Fatal("expected the handler of the declared topic to be called")
+	}
+}
+
+func TestExplicitTopicsUndeclared(t *testing.T) {
+	ps := New(WithExplicitTopics())
+
+	err := ps.Subscribe("testTopic", func(args ...any) {})
+	if !errors.Is(err, ErrUnknownTopic) {
+		t.Fatalf("expected Subscribe to return ErrUnknownTopic, got %v", err)
+	}
+	if err := ps.Publish("testTopic"); !errors.Is(err, ErrUnknownTopic) {
+		t.Fatalf("expected Publish to return ErrUnknownTopic, got %v", err)
+	}
+	if err := ps.Pause("testTopic"); !errors.Is(err, ErrUnknownTopic) {
+		t.Fatalf("expected Pause to return ErrUnknownTopic, got %v", err)
+	}
+	err = ps.WaitForSubscribers(context.Background(), "testTopic", 1)
+	if !errors.Is(err, ErrUnknownTopic) {
+		t.Fatalf("expected WaitForSubscribers to return ErrUnknownTopic, got %v", err)
+	}
+	if ps.HasTopic("testTopic") {
+		t.Fatal("expected no topic to be created")
+	}
+}
+
+func TestExplicitTopicsRequest(t *testing.T) {
+	ps := New(WithExplicitTopics())
+	if err := ps.DeclareTopic("testTopic"); err != nil {
+		t.Fatalf("DeclareTopic returned an error: %s", err.Error())
+	}
+	err := ps.Respond("testTopic", func(args ...any) []any {
+		return []any{"pong"}
+	})
+	if err != nil {
+		t.Fatalf("Respond returned an error: %s", err.Error())
+	}
+
+	reply, err := ps.Request("testTopic", time.Second)
+	if err != nil {
+		t.Fatalf("Request returned an error: %s", err.Error())
+	}
+	if len(reply) != 1 || reply[0] != "pong" {
+		t.Fatalf("expected the reply through an undeclared inbox, got %v", reply)
+	}
+}
+
+func TestImplicitTopics(t *testing.T) {
+	ps := New()
+	if err := ps.Subscribe("testTopic", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("otherTopic"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+}
+
+func TestExportImportTopics(t *testing.T) {
+	ps := New(WithExplicitTopics())
+	for _, topic := range []string{"orders", "payments", "shipping"} {
+		if err := ps.DeclareTopic(topic); err != nil {
+			t.Fatalf("DeclareTopic returned an error: %s", err.Error())
+		}
+	}
+
+	exported := ps.ExportTopics()
+	restored := New(WithExplicitTopics())
+	if err := restored.ImportTopics(exported); err != nil {
+		t.Fatalf("ImportTopics returned an error: %s", err.Error())
+	}
+
+	if !reflect.DeepEqual(restored.Topics(), ps.Topics()) {
+		t.Fatalf("expected topics %v after import, got %v", ps.Topics(), restored.Topics())
+	}
+	if err := restored.Subscribe("payments", func(args ...any) {}); err != nil {
+		t.Fatalf("expected an imported topic to be declared, Subscribe returned: %s", err.Error())
+	}
+}
+
+func TestImportTopicsInvalid(t *testing.T) {
+	ps := New(WithStrictTopics())
+
+	err := ps.ImportTopics([]string{"orders", "", "payments"})
+
+	if !errors.Is(err, ErrInvalidTopic) {
+		t.Fatalf("expected ErrInvalidTopic, got %v", err)
+	}
+	if topics := ps.Topics(); !reflect.DeepEqual(topics, []string{"orders", "payments"}) {
+		t.Fatalf("expected the valid topics to be imported, got %v", topics)
+	}
+}
@@ -0,0 +1,70 @@
+package pubsub
+
+// fairQueue holds the pending items of a dispatcher with fair scheduling. Messages are
+// kept in a FIFO queue per topic and served round-robin across topics, so a busy topic
+// can't starve the others. See WithFairScheduling.
+type fairQueue struct {
+	topics map[string][]dispatchItem
+	// ready lists the topics with pending messages in the order they are served next.
+	ready []string
+	// barriers are reached once every message enqueued before them has been delivered.
+	barriers []dispatchItem
+	// seq numbers the items in the order they were enqueued.
+	seq uint64
+}
+
+func (q *fairQueue) push(item dispatchItem) {
+	q.seq++
+	item.seq = q.seq
+	if item.barrier != nil {
+		q.barriers = append(q.barriers, item)
+		return
+	}
+	if q.topics == nil {
+		q.topics = make(map[string][]dispatchItem)
+	}
+	topic := item.msg.Topic
+	if len(q.topics[topic]) == 0 {
+		q.ready = append(q.ready, topic)
+	}
+	q.topics[topic] = append(q.topics[topic], item)
+}
+
+// pop removes and returns the next item: a barrier that was reached, or else the oldest
+// message of the next topic in turn.
+func (q *fairQueue) pop() dispatchItem {
+	if len(q.barriers) > 0 && q.barriers[0].seq < q.oldest() {
+		item := q.barriers[0]
+		q.barriers[0] = dispatchItem{}
+		q.barriers = q.barriers[1:]
+		return item
+	}
+	topic := q.ready[0]
+	q.ready = q.ready[1:]
+	queue := q.topics[topic]
+	item := queue[0]
+	queue[0] = dispatchItem{}
+	if len(queue) == 1 {
+		delete(q.topics, topic)
+	} else {
+		q.topics[topic] = queue[1:]
+		q.ready = append(q.ready, topic)
+	}
+	return item
+}
+
+// oldest returns the sequence number of the oldest pending message, or the largest
+// possible one if there is none. The oldest message of each topic is first in its queue.
+func (q *fairQueue) oldest() uint64 {
+	oldest := ^uint64(0)
+	for _, topic := range q.ready {
+		if seq := q.topics[topic][0].seq; seq < oldest {
+			oldest = seq
+		}
+	}
+	return oldest
+}
+
+func (q *fairQueue) len() int {
+	return len(q.ready) + len(q.barriers)
+}
@@ -0,0 +1,109 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFairScheduling(t *testing.T) {
+	ps := New(WithGlobalOrder(false), WithFairScheduling())
+	defer ps.Shutdown()
+	const flood, trickle = 100, 3
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var delivered []string
+	var wg sync.WaitGroup
+	wg.Add(flood + trickle)
+	for _, topic := range []string{"a", "b"} {
+		topic := topic
+		err := ps.Subscribe(topic, func(args ...any) {
+			if topic == "a" && args[0] == 0 {
+				close(started)
+				<-release
+			}
+			mu.Lock()
+			delivered = append(delivered, topic)
+			mu.Unlock()
+			wg.Done()
+		})
+		if err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	// Hold up the dispatcher with the first message, so the rest queue up behind it.
+	if err := ps.Publish("a", 0); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	<-started
+	for i := 1; i < flood; i++ {
+		if err := ps.Publish("a", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	for i := 0; i < trickle; i++ {
+		if err := ps.Publish("b", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	seen := 0
+	for i, topic := range delivered {
+		if topic != "b" {
+			continue
+		}
+		// Each message of b waits for at most one message of a.
+		if limit := 2*(seen+1) + 1; i > limit {
+			t.Fatalf("expected message %d of b within the first %d deliveries, got position %d", seen, limit+1, i)
+		}
+		seen++
+	}
+	if seen != trickle {
+		t.Fatalf("expected %d messages of b, got %d", trickle, seen)
+	}
+}
+
+func TestFairSchedulingDrain(t *testing.T) {
+	ps := New(WithGlobalOrder(false), WithFairScheduling())
+	defer ps.Shutdown()
+	var mu sync.Mutex
+	delivered := 0
+	for _, topic := range []string{"a", "b"} {
+		err := ps.Subscribe(topic, func(args ...any) {
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			delivered++
+			mu.Unlock()
+		})
+		if err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+	for i := 0; i < 10; i++ {
+		for _, topic := range []string{"a", "b"} {
+			if err := ps.Publish(topic, i); err != nil {
+				t.Fatalf("Publish returned an error: %s", err.Error())
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ps.Drain(ctx); err != nil {
+		t.Fatalf("Drain returned an error: %s", err.Error())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if delivered != 20 {
+		t.Fatalf("expected Drain to wait for all 20 messages, got %d", delivered)
+	}
+}
@@ -0,0 +1,55 @@
+package pubsub
+
+import "fmt"
+
+// HandlerError is an error returned by a handler subscribed with SubscribeErr, as
+// reported by TryPublish, or the failure of a handler published to the error topic set
+// with WithErrorTopic. Use errors.As to find out which handler failed.
+type HandlerError struct {
+	// Topic is the topic the message was published to.
+	Topic string
+	// Index is the position of the handler among the topic's handlers when the
+	// message was delivered, or among the default handlers for one added with
+	// SubscribeDefault. It is -1 for a handler added with SubscribeWithRetry, whose
+	// last attempt may be made long after the delivery.
+	Index int
+	// Err is the error the handler returned.
+	Err error
+	// Args are the args of the message the handler failed on.
+	Args []any
+}
+
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("pubsub: handler %d of topic %q: %s", e.Index, e.Topic, e.Err.Error())
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// SubscribeErr adds a handler that can fail to the topic. TryPublish returns the errors
+// of such handlers, each wrapped in a HandlerError and joined, once all handlers have
+// been called; Publish ignores them. On an asynchronous PubSub handlers run after
+// TryPublish returns, so their errors are ignored too. Either way, a message whose
+// handler failed is published to the dead-letter topic with the HandlerError, unless it
+// was published to the dead-letter topic itself.
+// The returned cancel function removes the handler from the topic.
+func (p *pubsub) SubscribeErr(topic string, handler func(...any) error) (cancel func(), err error) {
+	if handler == nil {
+		return nil, ErrNilHandler
+	}
+	return p.subscribe(topic, &subscription{
+		handler:   func(args ...any) { _ = handler(args...) },
+		handleErr: handler,
+	})
+}
+
+// failed publishes an Event with err, the failure of a handler, to the error topic, if
+// there is one. Failures of the error topic's own handlers are dropped.
+func (p *pubsub) failed(err *HandlerError) {
+	errorTopic := p.opts.errorTopic
+	if errorTopic == "" || err.Topic == errorTopic {
+		return
+	}
+	_ = p.Publish(errorTopic, p.newEvent(errorTopic, err))
+}
@@ -0,0 +1,206 @@
+package pubsub
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHandlerErrorIndex(t *testing.T) {
+	ps := New()
+	errFailed := errors.New("failed")
+	calls := 0
+	handlers := []func(...any) error{
+		func(args ...any) error { calls++; return nil },
+		func(args ...any) error { calls++; return errFailed },
+		func(args ...any) error { calls++; return nil },
+	}
+	for _, handler := range handlers {
+		if _, err := ps.SubscribeErr("testTopic", handler); err != nil {
+			t.Fatalf("SubscribeErr returned an error: %s", err.Error())
+		}
+	}
+
+	err := ps.TryPublish("testTopic", "payload")
+
+	if calls != 3 {
+		t.Fatalf("expected all handlers to be called, got %d calls", calls)
+	}
+	var handlerErr *HandlerError
+	if !errors.As(err, &handlerErr) {
+		t.Fatalf("expected a HandlerError, got %v", err)
+	}
+	if handlerErr.Topic != "testTopic" || handlerErr.Index != 1 {
+		t.Fatalf("expected handler 1 of testTopic to fail, got handler %d of %q", handlerErr.Index, handlerErr.Topic)
+	}
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("expected the handler's error to be wrapped, got %v", err)
+	}
+}
+
+func TestHandlerErrorJoined(t *testing.T) {
+	ps := New()
+	for i := 0; i < 3; i++ {
+		i := i
+		_, err := ps.SubscribeErr("testTopic", func(args ...any) error {
+			if i == 1 {
+				return nil
+			}
+			return errors.New("failed")
+		})
+		if err != nil {
+			t.Fatalf("SubscribeErr returned an error: %s", err.Error())
+		}
+	}
+
+	err := ps.TryPublish("testTopic")
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected joined errors, got %v", err)
+	}
+	var indexes []int
+	for _, err := range joined.Unwrap() {
+		var handlerErr *HandlerError
+		if !errors.As(err, &handlerErr) {
+			t.Fatalf("expected a HandlerError, got %v", err)
+		}
+		indexes = append(indexes, handlerErr.Index)
+	}
+	if len(indexes) != 2 || indexes[0] != 0 || indexes[1] != 2 {
+		t.Fatalf("expected handlers 0 and 2 to fail, got %v", indexes)
+	}
+}
+
+func TestHandlerErrorIgnoredByPublish(t *testing.T) {
+	ps := New()
+	_, err := ps.SubscribeErr("testTopic", func(args ...any) error {
+		return errors.New("failed")
+	})
+	if err != nil {
+		t.Fatalf("SubscribeErr returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("testTopic"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+}
+
+func TestErrorTopicPanic(t *testing.T) {
+	ps := New(WithErrorTopic("errors"))
+	var events []Event
+	if _, err := ps.SubscribeEvents("errors", func(event Event) { events = append(events, event) }); err != nil {
+		t.Fatalf("SubscribeEvents returned an error: %s", err.Error())
+	}
+	calls := 0
+	if err := ps.Subscribe("orders", func(args ...any) { calls++ }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("orders", func(args ...any) { panic("boom") }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	// The panic is recovered.
+	if err := ps.Publish("orders", "order", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if calls != 1 {
+		t.Fatalf("expected the other handler to be called, got %d calls", calls)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event on the error topic, got %d", len(events))
+	}
+	handlerErr, ok := events[0].Payload.(*HandlerError)
+	if !ok {
+		t.Fatalf("expected a *HandlerError payload, got %T", events[0].Payload)
+	}
+	if handlerErr.Topic != "orders" || handlerErr.Index != 1 {
+		t.Errorf("expected handler 1 of orders to fail, got handler %d of %q", handlerErr.Index, handlerErr.Topic)
+	}
+	if !errors.Is(handlerErr, ErrHandlerPanic) {
+		t.Errorf("expected ErrHandlerPanic, got %v", handlerErr.Err)
+	}
+	if want := []any{"order", 1}; !reflect.DeepEqual(handlerErr.Args, want) {
+		t.Errorf("expected the args %v, got %v", want, handlerErr.Args)
+	}
+}
+
+func TestErrorTopicHandlerError(t *testing.T) {
+	ps := New(WithErrorTopic("errors"), WithAsync(4))
+	defer ps.Shutdown()
+	received := make(chan *HandlerError, 1)
+	if _, err := ps.SubscribeEvents("errors", func(event Event) { received <- event.Payload.(*HandlerError) }); err != nil {
+		t.Fatalf("SubscribeEvents returned an error: %s", err.Error())
+	}
+	errFailed := errors.New("failed")
+	if _, err := ps.SubscribeErr("orders", func(args ...any) error { return errFailed }); err != nil {
+		t.Fatalf("SubscribeErr returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("orders", "order"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	select {
+	case handlerErr := <-received:
+		if handlerErr.Topic != "orders" || handlerErr.Index != 0 || !errors.Is(handlerErr, errFailed) {
+			t.Errorf("expected handler 0 of orders to fail with %v, got %v", errFailed, handlerErr)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the failure was not published to the error topic")
+	}
+}
+
+func TestErrorTopicNoRecursion(t *testing.T) {
+	ps := New(WithErrorTopic("errors"))
+	calls := 0
+	if err := ps.Subscribe("errors", func(args ...any) {
+		calls++
+		panic("error handler failed")
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("orders", func(args ...any) { panic("boom") }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("orders"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if calls != 1 {
+		t.Fatalf("expected the error topic's handler to be called once, got %d", calls)
+	}
+}
+
+func TestHandlerErrorDeadLetter(t *testing.T) {
+	ps := New(WithDeadLetter("dead"))
+	var dead [][]any
+	if err := ps.Subscribe("dead", func(args ...any) { dead = append(dead, args) }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	errFailed := errors.New("failed")
+	if _, err := ps.SubscribeErr("orders", func(args ...any) error { return errFailed }); err != nil {
+		t.Fatalf("SubscribeErr returned an error: %s", err.Error())
+	}
+	deadCalls := 0
+	if _, err := ps.SubscribeErr("dead", func(args ...any) error {
+		deadCalls++
+		return errFailed
+	}); err != nil {
+		t.Fatalf("SubscribeErr returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("orders", "order"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(dead) != 1 || len(dead[0]) != 2 || dead[0][0] != "order" {
+		t.Fatalf("expected the order to be dead-lettered once with the error, got %v", dead)
+	}
+	var handlerErr *HandlerError
+	if err, _ := dead[0][1].(error); !errors.As(err, &handlerErr) || handlerErr.Topic != "orders" || !errors.Is(err, errFailed) {
+		t.Errorf("expected a HandlerError of orders wrapping %v, got %v", errFailed, dead[0][1])
+	}
+	if deadCalls != 1 {
+		t.Errorf("expected the failing dead-letter handler not to dead-letter again, got %d calls", deadCalls)
+	}
+}
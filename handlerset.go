@@ -0,0 +1,93 @@
+package pubsub
+
+// handlerSet stores the subscriptions of a topic. It isn't safe for concurrent use;
+// the topic guards it with its mutex. Implementations other than the default
+// sliceHandlerSet, such as one indexed by subscription ID, can be swapped in newTopic.
+type handlerSet interface {
+	// add adds sub after the subscriptions already in the set.
+	add(sub *subscription)
+	// remove removes and returns the first subscription for which match returns true,
+	// or returns nil if there is none.
+	remove(match func(*subscription) bool) *subscription
+	// replace puts sub in the place of the first subscription for which match returns true
+	// and returns that one, or returns nil and leaves the set unchanged if there is none.
+	replace(match func(*subscription) bool, sub *subscription) *subscription
+	// removeAll removes and returns the subscriptions for which match returns true.
+	removeAll(match func(*subscription) bool) []*subscription
+	// clear removes and returns all subscriptions.
+	clear() []*subscription
+	// all returns the subscriptions in the order they were added, which is the order
+	// handlers are called in. Later changes to the set don't affect the returned slice,
+	// so it can be iterated without holding the topic's mutex. It must not be modified.
+	all() []*subscription
+	// len returns the number of subscriptions.
+	len() int
+}
+
+// sliceHandlerSet is the default handlerSet. Its slice is replaced rather than modified
+// in place when a subscription is removed, so all can return it without copying.
+type sliceHandlerSet struct {
+	subs []*subscription
+}
+
+func (s *sliceHandlerSet) add(sub *subscription) {
+	// Appending only writes past the end of the slices returned by all.
+	s.subs = append(s.subs, sub)
+}
+
+func (s *sliceHandlerSet) remove(match func(*subscription) bool) *subscription {
+	for i, sub := range s.subs {
+		if match(sub) {
+			subs := make([]*subscription, 0, len(s.subs)-1)
+			subs = append(subs, s.subs[:i]...)
+			s.subs = append(subs, s.subs[i+1:]...)
+			return sub
+		}
+	}
+	return nil
+}
+
+func (s *sliceHandlerSet) replace(match func(*subscription) bool, sub *subscription) *subscription {
+	for i, old := range s.subs {
+		if match(old) {
+			subs := append([]*subscription(nil), s.subs...)
+			subs[i] = sub
+			s.subs = subs
+			return old
+		}
+	}
+	return nil
+}
+
+func (s *sliceHandlerSet) removeAll(match func(*subscription) bool) []*subscription {
+	var subs, removed []*subscription
+	for i, sub := range s.subs {
+		matched := match(sub)
+		if matched {
+			removed = append(removed, sub)
+		}
+		if matched && subs == nil {
+			subs = append(make([]*subscription, 0, len(s.subs)-1), s.subs[:i]...)
+		} else if !matched && subs != nil {
+			subs = append(subs, sub)
+		}
+	}
+	if subs != nil {
+		s.subs = subs
+	}
+	return removed
+}
+
+func (s *sliceHandlerSet) clear() []*subscription {
+	subs := s.subs
+	s.subs = nil
+	return subs
+}
+
+func (s *sliceHandlerSet) all() []*subscription {
+	return s.subs
+}
+
+func (s *sliceHandlerSet) len() int {
+	return len(s.subs)
+}
@@ -0,0 +1,117 @@
+package pubsub
+
+import "testing"
+
+func newTestSubscriptions(n int) []*subscription {
+	subs := make([]*subscription, n)
+	for i := range subs {
+		subs[i] = &subscription{id: SubscriptionID(i + 1)}
+	}
+	return subs
+}
+
+func expectSubscriptions(t *testing.T, set handlerSet, want ...*subscription) {
+	t.Helper()
+	if n := set.len(); n != len(want) {
+		t.Fatalf("expected %d subscriptions, got %d", len(want), n)
+	}
+	got := set.all()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d subscriptions, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected subscription %d at index %d, got %d", want[i].id, i, got[i].id)
+		}
+	}
+}
+
+func TestHandlerSetAdd(t *testing.T) {
+	set := &sliceHandlerSet{}
+	subs := newTestSubscriptions(3)
+	for _, sub := range subs {
+		set.add(sub)
+	}
+
+	expectSubscriptions(t, set, subs...)
+}
+
+func TestHandlerSetRemove(t *testing.T) {
+	set := &sliceHandlerSet{}
+	subs := newTestSubscriptions(3)
+	for _, sub := range subs {
+		set.add(sub)
+	}
+	before := set.all()
+
+	removed := set.remove(func(s *subscription) bool { return s == subs[1] })
+
+	if removed != subs[1] {
+		t.Fatalf("expected the matching subscription to be returned, got %v", removed)
+	}
+	expectSubscriptions(t, set, subs[0], subs[2])
+	if before[1] != subs[1] {
+		t.Fatal("removing changed a slice returned by all before")
+	}
+	if removed := set.remove(func(s *subscription) bool { return s == subs[1] }); removed != nil {
+		t.Fatalf("expected nil when nothing matches, got %v", removed)
+	}
+}
+
+func TestHandlerSetRemoveAll(t *testing.T) {
+	set := &sliceHandlerSet{}
+	subs := newTestSubscriptions(4)
+	subs[0].once = true
+	subs[2].once = true
+	for _, sub := range subs {
+		set.add(sub)
+	}
+
+	removed := set.removeAll(func(s *subscription) bool { return s.once })
+
+	expectSubscriptions(t, set, subs[1], subs[3])
+	if len(removed) != 2 || removed[0] != subs[0] || removed[1] != subs[2] {
+		t.Fatalf("expected the removed subscriptions to be returned in order, got %v", removed)
+	}
+	if removed := set.removeAll(func(s *subscription) bool { return s.once }); removed != nil {
+		t.Fatalf("expected nothing to be removed, got %v", removed)
+	}
+}
+
+func TestHandlerSetClear(t *testing.T) {
+	set := &sliceHandlerSet{}
+	subs := newTestSubscriptions(2)
+	for _, sub := range subs {
+		set.add(sub)
+	}
+
+	cleared := set.clear()
+
+	if len(cleared) != 2 || cleared[0] != subs[0] || cleared[1] != subs[1] {
+		t.Fatalf("expected clear to return the subscriptions in order, got %v", cleared)
+	}
+	expectSubscriptions(t, set)
+}
+
+func TestHandlerSetReplace(t *testing.T) {
+	set := &sliceHandlerSet{}
+	subs := newTestSubscriptions(4)
+	for _, sub := range subs[:3] {
+		set.add(sub)
+	}
+	before := set.all()
+
+	replaced := set.replace(func(s *subscription) bool { return s == subs[1] }, subs[3])
+
+	if replaced != subs[1] {
+		t.Fatalf("expected the matching subscription to be returned, got %v", replaced)
+	}
+	expectSubscriptions(t, set, subs[0], subs[3], subs[2])
+	if before[1] != subs[1] {
+		t.Fatal("replacing changed a slice returned by all before")
+	}
+	if replaced := set.replace(func(s *subscription) bool { return s == subs[1] }, subs[1]); replaced != nil {
+		t.Fatalf("expected nil when nothing matches, got %v", replaced)
+	}
+	expectSubscriptions(t, set, subs[0], subs[3], subs[2])
+}
@@ -0,0 +1,51 @@
+package pubsub
+
+import "sync"
+
+// history is a ring buffer of the last messages published to a topic.
+type history struct {
+	mu   sync.Mutex
+	msgs [][]any
+	// next is the index the next message is stored at once msgs is full.
+	next int
+}
+
+func newHistory(size int) *history {
+	return &history{msgs: make([][]any, 0, size)}
+}
+
+func (h *history) record(args []any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.msgs) < cap(h.msgs) {
+		h.msgs = append(h.msgs, args)
+		return
+	}
+	h.msgs[h.next] = args
+	h.next = (h.next + 1) % len(h.msgs)
+}
+
+// last returns up to the last n messages, oldest first.
+func (h *history) last(n int) [][]any {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n > len(h.msgs) {
+		n = len(h.msgs)
+	}
+	msgs := make([][]any, 0, n)
+	for i := len(h.msgs) - n; i < len(h.msgs); i++ {
+		msgs = append(msgs, h.msgs[(h.next+i)%len(h.msgs)])
+	}
+	return msgs
+}
+
+// History returns up to the last n messages published to the topic, newest last.
+// It returns an empty slice unless the PubSub was created with WithHistory, and for
+// unknown topics. The returned args slices are shared with the handlers.
+func (p *pubsub) History(topic string, n int) [][]any {
+	t, ok := p.lookupTopic(topic)
+	if !ok || t.history == nil || n <= 0 {
+		return [][]any{}
+	}
+	return t.history.last(n)
+}
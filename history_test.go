@@ -0,0 +1,51 @@
+package pubsub
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	ps := New(WithHistory(3))
+
+	// Messages are recorded even without handlers.
+	for i := 1; i <= 5; i++ {
+		if err := ps.Publish("testTopic", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+
+	tests := []struct {
+		n    int
+		want [][]any
+	}{
+		{n: 1, want: [][]any{{5}}},
+		{n: 2, want: [][]any{{4}, {5}}},
+		{n: 3, want: [][]any{{3}, {4}, {5}}},
+		{n: 10, want: [][]any{{3}, {4}, {5}}},
+		{n: 0, want: [][]any{}},
+	}
+	for _, tt := range tests {
+		if got := ps.History("testTopic", tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("History(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryEmpty(t *testing.T) {
+	ps := New(WithHistory(3))
+	if got := ps.History("unknownTopic", 3); got == nil || len(got) != 0 {
+		t.Errorf("expected an empty slice for an unknown topic, got %#v", got)
+	}
+
+	ps = New()
+	if err := ps.Subscribe("testTopic", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if got := ps.History("testTopic", 3); len(got) != 0 {
+		t.Errorf("expected no history without WithHistory, got %v", got)
+	}
+}
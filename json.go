@@ -0,0 +1,49 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrNotJSON is passed to the error callback of a JSONHandler whose message doesn't have
+// a []byte or string as its first argument.
+var ErrNotJSON = errors.New("pubsub: message is not JSON")
+
+// JSONHandler adapts fn into a handler that decodes the first argument of each message,
+// a []byte or string holding JSON, into a T and calls fn with it. Messages that can't be
+// decoded are passed to onError with the error, if it is given, and dropped otherwise.
+func JSONHandler[T any](fn func(T), onError ...func(args []any, err error)) func(...any) {
+	fail := func(args []any, err error) {
+		for _, onError := range onError {
+			onError(args, err)
+		}
+	}
+	return func(args ...any) {
+		var data []byte
+		if len(args) > 0 {
+			switch arg := args[0].(type) {
+			case []byte:
+				data = arg
+			case json.RawMessage:
+				data = arg
+			case string:
+				data = []byte(arg)
+			}
+		}
+		if data == nil {
+			if len(args) == 0 {
+				fail(args, fmt.Errorf("%w: no arguments", ErrNotJSON))
+			} else {
+				fail(args, fmt.Errorf("%w: got %T", ErrNotJSON, args[0]))
+			}
+			return
+		}
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			fail(args, err)
+			return
+		}
+		fn(v)
+	}
+}
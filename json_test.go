@@ -0,0 +1,68 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type order struct {
+	ID    string `json:"id"`
+	Total int    `json:"total"`
+}
+
+func TestJSONHandler(t *testing.T) {
+	ps := New()
+	var received []order
+	handler := JSONHandler(func(o order) {
+		received = append(received, o)
+	}, func(args []any, err error) {
+		t.Errorf("unexpected decode error: %s", err.Error())
+	})
+	if err := ps.Subscribe("orders", handler); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("orders", []byte(`{"id":"a","total":3}`)); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("orders", `{"id":"b","total":5}`); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+
+	want := []order{{ID: "a", Total: 3}, {ID: "b", Total: 5}}
+	if len(received) != 2 || received[0] != want[0] || received[1] != want[1] {
+		t.Fatalf("expected %v, got %v", want, received)
+	}
+}
+
+func TestJSONHandlerMalformed(t *testing.T) {
+	ps := New()
+	var errs []error
+	handler := JSONHandler(func(o order) {
+		t.Errorf("handler called with %v", o)
+	}, func(args []any, err error) {
+		errs = append(errs, err)
+	})
+	if err := ps.Subscribe("orders", handler); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("orders", []byte(`{"id":`)); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("orders", 42); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 decode errors, got %v", errs)
+	}
+	var syntaxErr *json.SyntaxError
+	if !errors.As(errs[0], &syntaxErr) {
+		t.Errorf("expected a JSON syntax error, got %v", errs[0])
+	}
+	if !errors.Is(errs[1], ErrNotJSON) {
+		t.Errorf("expected ErrNotJSON, got %v", errs[1])
+	}
+}
@@ -0,0 +1,64 @@
+package pubsub
+
+import "sync"
+
+// SubscribeKeyed adds a handler to the topic that processes messages asynchronously.
+// Messages for which keyFn returns the same key are handled one at a time in publish
+// order, while messages with different keys are handled in parallel by up to
+// concurrency goroutines. A concurrency below 1 is treated as 1. A nil keyFn, like a nil
+// handler, returns ErrNilHandler.
+func (p *pubsub) SubscribeKeyed(topic string, keyFn func(args ...any) string, concurrency int, handler func(...any)) error {
+	if handler == nil || keyFn == nil {
+		return ErrNilHandler
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	d := &keyedDispatcher{
+		keyFn:   keyFn,
+		handler: handler,
+		sem:     make(chan struct{}, concurrency),
+		queues:  make(map[string][][]any),
+	}
+	return p.Subscribe(topic, d.dispatch)
+}
+
+// keyedDispatcher keeps a queue of pending messages per key.
+// A key has an entry in queues for as long as a goroutine is draining it.
+type keyedDispatcher struct {
+	keyFn   func(args ...any) string
+	handler func(...any)
+	sem     chan struct{}
+
+	mu     sync.Mutex
+	queues map[string][][]any
+}
+
+func (d *keyedDispatcher) dispatch(args ...any) {
+	key := d.keyFn(args...)
+	d.mu.Lock()
+	queue, draining := d.queues[key]
+	d.queues[key] = append(queue, args)
+	d.mu.Unlock()
+	if !draining {
+		go d.drain(key)
+	}
+}
+
+func (d *keyedDispatcher) drain(key string) {
+	d.sem <- struct{}{}
+	defer func() { <-d.sem }()
+	for {
+		d.mu.Lock()
+		queue := d.queues[key]
+		if len(queue) == 0 {
+			delete(d.queues, key)
+			d.mu.Unlock()
+			return
+		}
+		args := queue[0]
+		d.queues[key] = queue[1:]
+		d.mu.Unlock()
+		d.handler(args...)
+	}
+}
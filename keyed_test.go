@@ -0,0 +1,76 @@
+package pubsub
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubscribeKeyed(t *testing.T) {
+	ps := New()
+	const perKey = 50
+
+	var mu sync.Mutex
+	seen := make(map[string][]int)
+	var wg sync.WaitGroup
+	wg.Add(2 * perKey)
+
+	// The first message of key "a" blocks until key "b" has been handled,
+	// which can only happen if the two keys are processed concurrently.
+	bStarted := make(chan struct{})
+	var once sync.Once
+
+	err := ps.SubscribeKeyed("orders", func(args ...any) string {
+		return args[0].(string)
+	}, 2, func(args ...any) {
+		defer wg.Done()
+		key, n := args[0].(string), args[1].(int)
+		if key == "a" && n == 0 {
+			select {
+			case <-bStarted:
+			case <-time.After(time.Second):
+				t.Error("keys were not processed concurrently")
+			}
+		}
+		if key == "b" {
+			once.Do(func() { close(bStarted) })
+		}
+		mu.Lock()
+		seen[key] = append(seen[key], n)
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("SubscribeKeyed returned an error: %s", err.Error())
+	}
+
+	for i := 0; i < perKey; i++ {
+		for _, key := range []string{"a", "b"} {
+			if err := ps.Publish("orders", key, i); err != nil {
+				t.Fatalf("Publish returned an error: %s", err.Error())
+			}
+		}
+	}
+	wg.Wait()
+
+	for _, key := range []string{"a", "b"} {
+		if len(seen[key]) != perKey {
+			t.Fatalf("expected %d messages for key %s, got %d", perKey, key, len(seen[key]))
+		}
+		for i, n := range seen[key] {
+			if n != i {
+				t.Fatalf("key %s: expected message %d at position %d, got %d", key, i, i, n)
+			}
+		}
+	}
+}
+
+func TestSubscribeKeyedNilKeyFn(t *testing.T) {
+	ps := New()
+	if err := ps.SubscribeKeyed("testTopic", nil, 1, func(args ...any) {}); !errors.Is(err, ErrNilHandler) {
+		t.Fatalf("expected ErrNilHandler for a nil key function, got %v", err)
+	}
+	if ps.HasTopic("testTopic") {
+		t.Error("expected no topic to be created")
+	}
+}
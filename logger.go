@@ -0,0 +1,15 @@
+package pubsub
+
+// Logger is the interface the PubSub logs lifecycle and delivery events to.
+type Logger interface {
+	Debugf(format string, args ...any)
+}
+
+// logEvent logs an event on the topic along with its number of handlers.
+// It takes concrete arguments so that nothing is allocated when no Logger is configured.
+func (p *pubsub) logEvent(event, topic string, handlers int) {
+	if p.opts.logger == nil {
+		return
+	}
+	p.opts.logger.Debugf("pubsub: %s topic=%q handlers=%d", event, topic, handlers)
+}
@@ -0,0 +1,47 @@
+package pubsub
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type captureLogger struct {
+	lines []string
+}
+
+func (l *captureLogger) Debugf(format string, args ...any) {
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func TestLogger(t *testing.T) {
+	logger := &captureLogger{}
+	ps := New(WithLogger(logger))
+
+	if err := ps.Subscribe("orders", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("orders", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("orders", "created"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("unknown", "created"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if err := ps.CloseTopic("orders"); err != nil {
+		t.Fatalf("CloseTopic returned an error: %s", err.Error())
+	}
+
+	want := []string{
+		`pubsub: subscribe topic="orders" handlers=1`,
+		`pubsub: subscribe topic="orders" handlers=2`,
+		`pubsub: publish topic="orders" handlers=2`,
+		`pubsub: publish topic="unknown" handlers=0`,
+		`pubsub: close topic="orders" handlers=0`,
+	}
+	if !reflect.DeepEqual(logger.lines, want) {
+		t.Errorf("expected log lines %q, got %q", want, logger.lines)
+	}
+}
@@ -0,0 +1,89 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// ErrPublishLoop is returned when handlers publish to each other, directly or through
+// other topics, more deeply nested than the limit set by WithMaxPublishDepth.
+var ErrPublishLoop = errors.New("pubsub: publish loop")
+
+// invokePC is the entry of topic.invoke, which is on the stack once per delivery in progress.
+// handedOffPC is the entry of handedOff, which is on the stack of a goroutine a delivery is
+// handed off to once per delivery in progress on the goroutine that handed it off.
+// They are set in init because invoke indirectly refers to them.
+var invokePC, handedOffPC uintptr
+
+func init() {
+	invokePC = reflect.ValueOf((*topic).invoke).Pointer()
+	handedOffPC = reflect.ValueOf(handedOff).Pointer()
+}
+
+// publishDepth returns how many deliveries are in progress on the calling goroutine,
+// including those it continues from another goroutine with atDepth, that is how deeply
+// nested the publish it is called from is.
+func publishDepth() int {
+	var pcs [64]uintptr
+	depth := 0
+	for skip := 2; ; skip += len(pcs) {
+		n := runtime.Callers(skip, pcs[:])
+		for _, pc := range pcs[:n] {
+			if f := runtime.FuncForPC(pc - 1); f != nil && (f.Entry() == invokePC || f.Entry() == handedOffPC) {
+				depth++
+			}
+		}
+		if n < len(pcs) {
+			return depth
+		}
+	}
+}
+
+// loops reports whether delivering to the topic would exceed the maximum publish depth.
+// Each nested delivery is in progress until the ones it contains are done, so the stack is
+// only inspected once at least as many deliveries as the limit are in progress.
+// It must be called with mu held.
+func (t *topic) loops() bool {
+	limit := t.p.opts.maxPublishDepth
+	return limit > 0 && t.p.inFlight.Load() >= int64(limit) && publishDepth() >= limit
+}
+
+// handOffDepth returns the publish depth of the calling goroutine, which is inside invoke,
+// for a goroutine the delivery is handed off to to continue at with atDepth.
+func (t *topic) handOffDepth() int {
+	if t.p.opts.maxPublishDepth <= 0 {
+		return 0
+	}
+	if t.p.inFlight.Load() <= 1 {
+		// The delivery in progress is the only one.
+		return 1
+	}
+	return publishDepth()
+}
+
+// atDepth calls f with depth frames of handedOff on the stack, so that publishDepth
+// counts the deliveries in progress on the goroutine f was handed off from.
+func atDepth(depth int, f func()) {
+	if depth <= 0 {
+		f()
+		return
+	}
+	handedOff(depth, f)
+}
+
+// handedOff is never inlined, so publishDepth can find it on the stack.
+//
+//go:noinline
+func handedOff(depth int, f func()) {
+	if depth > 1 {
+		handedOff(depth-1, f)
+		return
+	}
+	f()
+}
+
+func (t *topic) loopError() error {
+	return fmt.Errorf("%w: more than %d nested publishes reaching topic %q", ErrPublishLoop, t.p.opts.maxPublishDepth, t.name)
+}
@@ -0,0 +1,109 @@
+package pubsub
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestPublishLoop(t *testing.T) {
+	ps := New(WithMaxPublishDepth(8))
+	var errs []error
+	var calls int
+	pingPong := func(to string) func(...any) {
+		return func(args ...any) {
+			calls++
+			if err := ps.Publish(to); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if err := ps.Subscribe("ping", pingPong("pong")); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("pong", pingPong("ping")); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("ping"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if calls != 8 {
+		t.Errorf("expected 8 nested deliveries, got %d", calls)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrPublishLoop) {
+		t.Errorf("expected a single ErrPublishLoop, got %v", errs)
+	}
+}
+
+func TestPublishLoopDefault(t *testing.T) {
+	ps := New()
+	var err error
+	if err := ps.Subscribe("testTopic", func(args ...any) {
+		if e := ps.Publish("testTopic"); e != nil {
+			err = e
+		}
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if !errors.Is(err, ErrPublishLoop) {
+		t.Errorf("expected ErrPublishLoop, got %v", err)
+	}
+}
+
+func TestPublishNestedWithinDepth(t *testing.T) {
+	ps := New()
+	var calls int
+	if err := ps.Subscribe("testTopic", func(args ...any) {
+		calls++
+		if n := args[0].(int); n > 1 {
+			if err := ps.Publish("testTopic", n-1); err != nil {
+				t.Errorf("Publish returned an error: %s", err.Error())
+			}
+		}
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic", 10); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if calls != 10 {
+		t.Errorf("expected 10 deliveries, got %d", calls)
+	}
+}
+
+func TestPublishLoopWorkers(t *testing.T) {
+	ps := New(WithWorkers(2), WithMaxPublishDepth(8))
+	var mu sync.Mutex
+	var errs []error
+	var calls int
+	for i := 0; i < 2; i++ {
+		if err := ps.Subscribe("testTopic", func(args ...any) {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			if err := ps.Publish("testTopic"); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	if err := ps.Publish("testTopic"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	// Each delivery calls both handlers, so there are 2+4+...+2^8 calls and the 2^8 calls
+	// at the deepest level fail to publish.
+	if calls != 510 {
+		t.Errorf("expected 510 calls, got %d", calls)
+	}
+	if len(errs) != 256 || !errors.Is(errs[0], ErrPublishLoop) {
+		t.Errorf("expected 256 ErrPublishLoop errors, got %d: %v", len(errs), errs[0])
+	}
+}
@@ -0,0 +1,97 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// operationNames maps each Operation to its JSON representation, the snake_case form of
+// its name, such as "subscribe_once" for SubscribeOnce.
+var operationNames = func() (names [len(operationStrings)]string) {
+	for op, name := range operationStrings {
+		names[op] = snakeCase(name)
+	}
+	return names
+}()
+
+// snakeCase converts a CamelCase name to snake_case.
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// MarshalJSON encodes the operation as a string such as "publish".
+func (o Operation) MarshalJSON() ([]byte, error) {
+	if o < 0 || int(o) >= len(operationNames) {
+		return nil, fmt.Errorf("pubsub: cannot marshal unknown operation %s", o)
+	}
+	return json.Marshal(operationNames[o])
+}
+
+// UnmarshalJSON decodes an operation encoded by MarshalJSON.
+func (o *Operation) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for op, n := range operationNames {
+		if n == name {
+			*o = Operation(op)
+			return nil
+		}
+	}
+	return fmt.Errorf("pubsub: cannot unmarshal unknown operation %q", name)
+}
+
+// jsonMessage is the wire format of a Message.
+type jsonMessage struct {
+	Topic     string    `json:"topic"`
+	Operation Operation `json:"operation"`
+	Args      []any     `json:"args,omitempty"`
+}
+
+// MarshalMessage encodes m as JSON.
+// Handlers can't be serialized, so the Args of subscribe operations are omitted.
+// The Args of other operations must be encodable by encoding/json.
+func MarshalMessage(m Message) ([]byte, error) {
+	jm := jsonMessage{
+		Topic:     m.Topic,
+		Operation: m.Operation,
+		Args:      m.Args,
+	}
+	switch m.Operation {
+	case Subscribe, SubscribeOnce, SubscribeOnceEach:
+		jm.Args = nil
+	}
+	data, err := json.Marshal(jm)
+	if err != nil {
+		return nil, fmt.Errorf("pubsub: marshal message: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalMessage decodes a message encoded by MarshalMessage.
+// Args are decoded into the types encoding/json uses for interface values,
+// so numbers become float64 and objects become map[string]any.
+func UnmarshalMessage(data []byte) (Message, error) {
+	var jm jsonMessage
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return Message{}, fmt.Errorf("pubsub: unmarshal message: %w", err)
+	}
+	return Message{
+		Topic:     jm.Topic,
+		Operation: jm.Operation,
+		Args:      jm.Args,
+	}, nil
+}
@@ -0,0 +1,89 @@
+package pubsub
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Message
+		want Message
+	}{
+		{
+			name: "publish",
+			in:   Message{Topic: "orders", Operation: Publish, Args: []any{"created", 42.0, true, nil}},
+			want: Message{Topic: "orders", Operation: Publish, Args: []any{"created", 42.0, true, nil}},
+		},
+		{
+			name: "subscribe omits handler",
+			in:   Message{Topic: "orders", Operation: Subscribe, Args: []any{func(...any) {}}},
+			want: Message{Topic: "orders", Operation: Subscribe},
+		},
+		{
+			name: "shutdown",
+			in:   Message{Operation: Shutdown},
+			want: Message{Operation: Shutdown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := MarshalMessage(tt.in)
+			if err != nil {
+				t.Fatalf("MarshalMessage returned an error: %s", err.Error())
+			}
+			got, err := UnmarshalMessage(data)
+			if err != nil {
+				t.Fatalf("UnmarshalMessage returned an error: %s", err.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMarshalMessageOperationName(t *testing.T) {
+	data, err := MarshalMessage(Message{Topic: "orders", Operation: Publish})
+	if err != nil {
+		t.Fatalf("MarshalMessage returned an error: %s", err.Error())
+	}
+	if !strings.Contains(string(data), `"operation":"publish"`) {
+		t.Errorf("expected the operation to be encoded by name, got %s", data)
+	}
+}
+
+func TestOperationNames(t *testing.T) {
+	want := map[Operation]string{
+		Subscribe:         "subscribe",
+		SubscribeOnce:     "subscribe_once",
+		SubscribeOnceEach: "subscribe_once_each",
+		Publish:           "publish",
+		TryPublish:        "try_publish",
+		Unsubscribe:       "unsubscribe",
+		UnsubscribeAll:    "unsubscribe_all",
+		CloseTopic:        "close_topic",
+		Shutdown:          "shutdown",
+	}
+	for op, name := range want {
+		if got := operationNames[op]; got != name {
+			t.Errorf("expected %s to be encoded as %q, got %q", op, name, got)
+		}
+	}
+}
+
+func TestMarshalMessageNonEncodableArg(t *testing.T) {
+	_, err := MarshalMessage(Message{Topic: "orders", Operation: Publish, Args: []any{make(chan int)}})
+	if err == nil {
+		t.Error("expected an error for a non-encodable arg")
+	}
+}
+
+func TestUnmarshalMessageUnknownOperation(t *testing.T) {
+	_, err := UnmarshalMessage([]byte(`{"topic":"orders","operation":"explode"}`))
+	if err == nil {
+		t.Error("expected an error for an unknown operation")
+	}
+}
@@ -0,0 +1,38 @@
+package pubsub
+
+// MetricsSink receives the metrics of a PubSub, which can be adapted to a metrics library
+// such as Prometheus without this package depending on it. Labels are label values; all
+// metrics but MetricTopics are labelled with the topic. See WithMetrics.
+type MetricsSink interface {
+	IncCounter(name string, labels ...string)
+	SetGauge(name string, v float64, labels ...string)
+}
+
+// The names of the metrics passed to a MetricsSink.
+const (
+	// MetricPublishes counts the messages published to each topic.
+	MetricPublishes = "pubsub_publishes_total"
+	// MetricDeliveries counts the handler calls of each topic.
+	MetricDeliveries = "pubsub_deliveries_total"
+	// MetricDrops counts the messages of each topic that were discarded, see DroppedCount.
+	MetricDrops = "pubsub_drops_total"
+	// MetricErrors counts the handlers of each topic that panicked or returned an error.
+	MetricErrors = "pubsub_errors_total"
+	// MetricTopics is the gauge of the number of open topics.
+	MetricTopics = "pubsub_topics"
+)
+
+// count increments the counter name of the topic, if the PubSub has a metrics sink.
+func (p *pubsub) count(name, topic string) {
+	if p.opts.metrics != nil {
+		p.opts.metrics.IncCounter(name, topic)
+	}
+}
+
+// topicsChanged adds delta to the number of open topics and reports it to the metrics sink.
+func (p *pubsub) topicsChanged(delta int64) {
+	n := p.openTopics.Add(delta)
+	if p.opts.metrics != nil {
+		p.opts.metrics.SetGauge(MetricTopics, float64(n))
+	}
+}
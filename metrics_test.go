@@ -0,0 +1,75 @@
+package pubsub
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSink records the metrics it receives.
+type fakeSink struct {
+	mu       sync.Mutex
+	counters map[string]int
+	gauges   map[string]float64
+}
+
+func newFakeSink() *fakeSink {
+	return &fakeSink{counters: make(map[string]int), gauges: make(map[string]float64)}
+}
+
+func (s *fakeSink) IncCounter(name string, labels ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counters[strings.Join(append([]string{name}, labels...), " ")]++
+}
+
+func (s *fakeSink) SetGauge(name string, v float64, labels ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gauges[strings.Join(append([]string{name}, labels...), " ")] = v
+}
+
+func TestMetrics(t *testing.T) {
+	sink := newFakeSink()
+	ps := New(WithMetrics(sink))
+	for i := 0; i < 2; i++ {
+		if err := ps.Subscribe("orders", func(args ...any) {}); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+	_, err := ps.SubscribeErr("orders", func(args ...any) error {
+		return errors.New("failed")
+	})
+	if err != nil {
+		t.Fatalf("SubscribeErr returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("orders", "payload"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("unheard"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+
+	wantCounters := map[string]int{
+		"pubsub_publishes_total orders":  1,
+		"pubsub_publishes_total unheard": 1,
+		"pubsub_deliveries_total orders": 3,
+		"pubsub_errors_total orders":     1,
+	}
+	if !reflect.DeepEqual(sink.counters, wantCounters) {
+		t.Fatalf("expected counters %v, got %v", wantCounters, sink.counters)
+	}
+	if n := sink.gauges[MetricTopics]; n != 1 {
+		t.Fatalf("expected 1 open topic, got %v", n)
+	}
+
+	if err := ps.CloseTopic("orders"); err != nil {
+		t.Fatalf("CloseTopic returned an error: %s", err.Error())
+	}
+	if n := sink.gauges[MetricTopics]; n != 0 {
+		t.Fatalf("expected no open topics after closing, got %v", n)
+	}
+}
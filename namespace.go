@@ -0,0 +1,426 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Namespace returns a view of the PubSub in which every topic name is prefixed with
+// prefix and a dot, so "orders" in a namespace "billing" is the topic "billing.orders"
+// of the PubSub. Components sharing a PubSub through different namespaces can't collide.
+// Methods that list or report topics, such as Topics and Snapshot, only cover the
+// namespace's topics and return their names without the prefix. UnsubscribeAll,
+// Shutdown and Reset only affect the namespace's topics, and Clone clones the whole
+// PubSub and returns the same namespace of the clone. Namespaces can be nested.
+func (p *pubsub) Namespace(prefix string) PubSub {
+	return &namespace{p: p, prefix: prefix + "."}
+}
+
+// namespace is a PubSub that prefixes all topic names before passing them on to p.
+type namespace struct {
+	p *pubsub
+	// prefix includes the trailing dot.
+	prefix string
+}
+
+// name returns the name of the topic in p.
+func (n *namespace) name(topic string) string {
+	return n.prefix + topic
+}
+
+// names returns the names of the topics in p.
+func (n *namespace) names(topics []string) []string {
+	names := make([]string, len(topics))
+	for i, topic := range topics {
+		names[i] = n.name(topic)
+	}
+	return names
+}
+
+// local returns the name of a topic of p in the namespace and whether it belongs to it.
+func (n *namespace) local(name string) (string, bool) {
+	if !strings.HasPrefix(name, n.prefix) {
+		return "", false
+	}
+	return name[len(n.prefix):], true
+}
+
+// topics returns the topics of p that belong to the namespace.
+func (n *namespace) topics() []*topic {
+	var topics []*topic
+	for _, t := range n.p.allTopics() {
+		if _, ok := n.local(t.name); ok {
+			topics = append(topics, t)
+		}
+	}
+	return topics
+}
+
+func (n *namespace) Subscribe(topic string, handler func(...any)) error {
+	return n.p.Subscribe(n.name(topic), handler)
+}
+
+func (n *namespace) SubscribeOnce(topic string, handler func(...any)) (cancel func(), err error) {
+	return n.p.SubscribeOnce(n.name(topic), handler)
+}
+
+func (n *namespace) SubscribeOnceEach(topic string, handler func(...any)) (cancel func(), err error) {
+	return n.p.SubscribeOnceEach(n.name(topic), handler)
+}
+
+func (n *namespace) SubscribeWithClose(topic string, onMsg func(...any), onClose func()) (cancel func(), err error) {
+	return n.p.SubscribeWithClose(n.name(topic), onMsg, onClose)
+}
+
+func (n *namespace) SubscribeOnceEachKeyed(topic, key string, handler func(...any)) error {
+	return n.p.SubscribeOnceEachKeyed(n.name(topic), key, handler)
+}
+
+func (n *namespace) SubscribeStateful2(topic string, snapshot func() []any, handler func(...any)) error {
+	return n.p.SubscribeStateful2(n.name(topic), snapshot, handler)
+}
+
+func (n *namespace) SubscribeKeyed(topic string, keyFn func(args ...any) string, concurrency int, handler func(...any)) error {
+	return n.p.SubscribeKeyed(n.name(topic), keyFn, concurrency, handler)
+}
+
+func (n *namespace) SubscribeReliable(topic string, handler func(args []any, ack func())) error {
+	return n.p.SubscribeReliable(n.name(topic), handler)
+}
+
+func (n *namespace) SubscribeFiltered(topic string, filter func(args ...any) bool, handler func(...any)) (cancel func(), err error) {
+	return n.p.SubscribeFiltered(n.name(topic), filter, handler)
+}
+
+func (n *namespace) SubscribeAffine(topic string, handler func(...any)) error {
+	return n.p.SubscribeAffine(n.name(topic), handler)
+}
+
+func (n *namespace) SubscribePartition(topic string, partition int, handler func(...any)) error {
+	return n.p.SubscribePartition(n.name(topic), partition, handler)
+}
+
+func (n *namespace) SubscribeErr(topic string, handler func(...any) error) (cancel func(), err error) {
+	return n.p.SubscribeErr(n.name(topic), handler)
+}
+
+// SubscribeDefault adds a catch-all handler for the namespace's topics without handlers.
+func (n *namespace) SubscribeDefault(handler func(topic string, args ...any)) (cancel func(), err error) {
+	if handler == nil {
+		return nil, ErrNilHandler
+	}
+	return n.p.SubscribeDefault(func(topic string, args ...any) {
+		if topic, ok := n.local(topic); ok {
+			handler(topic, args...)
+		}
+	})
+}
+
+func (n *namespace) SubscribeWithRetry(topic string, attempts int, backoff time.Duration, handler func(...any) error) (cancel func(), err error) {
+	return n.p.SubscribeWithRetry(n.name(topic), attempts, backoff, handler)
+}
+
+func (n *namespace) SubscribeMany(topics []string, handler func(...any)) (cancel func(), err error) {
+	return n.p.SubscribeMany(n.names(topics), handler)
+}
+
+func (n *namespace) SubscribeMerged(topics []string, handler func(topic string, args ...any)) (cancel func(), err error) {
+	if handler == nil {
+		return nil, ErrNilHandler
+	}
+	return n.p.SubscribeMerged(n.names(topics), func(topic string, args ...any) {
+		topic, _ = n.local(topic)
+		handler(topic, args...)
+	})
+}
+
+func (n *namespace) SubscribeWithID(topic string, handler func(...any)) (SubscriptionID, error) {
+	return n.p.SubscribeWithID(n.name(topic), handler)
+}
+
+func (n *namespace) SubscribeUntil(ctx context.Context, topic string, handler func(...any)) error {
+	return n.p.SubscribeUntil(ctx, n.name(topic), handler)
+}
+
+// SubscribeEvents passes the handler the events with their Topic relative to the namespace.
+func (n *namespace) SubscribeEvents(topic string, handler func(Event)) (cancel func(), err error) {
+	if handler == nil {
+		return nil, ErrNilHandler
+	}
+	return n.p.SubscribeEvents(n.name(topic), func(event Event) {
+		if local, ok := n.local(event.Topic); ok {
+			event.Topic = local
+		}
+		handler(event)
+	})
+}
+
+func (n *namespace) SubscribeCoalesced(topic string, window time.Duration, handler func(lastArgs ...any)) (cancel func(), err error) {
+	return n.p.SubscribeCoalesced(n.name(topic), window, handler)
+}
+
+func (n *namespace) SubscribeBatch(topic string, maxBatch int, maxWait time.Duration, handler func(batch [][]any)) (cancel func(), err error) {
+	return n.p.SubscribeBatch(n.name(topic), maxBatch, maxWait, handler)
+}
+
+func (n *namespace) Unsubscribe(topic string) error {
+	return n.p.Unsubscribe(n.name(topic))
+}
+
+func (n *namespace) UnsubscribeByID(topic string, id SubscriptionID) error {
+	return n.p.UnsubscribeByID(n.name(topic), id)
+}
+
+func (n *namespace) UnsubscribeHandler(topic string, handler func(...any)) error {
+	return n.p.UnsubscribeHandler(n.name(topic), handler)
+}
+
+func (n *namespace) Next(ctx context.Context, topic string) ([]any, error) {
+	return n.p.Next(ctx, n.name(topic))
+}
+
+func (n *namespace) SelectNext(ctx context.Context, topics ...string) (topic string, args []any, err error) {
+	topic, args, err = n.p.SelectNext(ctx, n.names(topics)...)
+	topic, _ = n.local(topic)
+	return topic, args, err
+}
+
+// UnsubscribeAll removes all handlers from the namespace's topics and closes them.
+func (n *namespace) UnsubscribeAll() error {
+	var errs []error
+	for _, t := range n.topics() {
+		if err := t.unsubscribe(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *namespace) Publish(topic string, args ...any) error {
+	return n.p.Publish(n.name(topic), args...)
+}
+
+func (n *namespace) TryPublish(topic string, args ...any) error {
+	return n.p.TryPublish(n.name(topic), args...)
+}
+
+func (n *namespace) PublishWait(ctx context.Context, topic string, args ...any) error {
+	return n.p.PublishWait(ctx, n.name(topic), args...)
+}
+
+func (n *namespace) PublishMany(topics []string, args ...any) error {
+	return n.p.PublishMany(n.names(topics), args...)
+}
+
+func (n *namespace) PublishAfter(topic string, delay time.Duration, args ...any) (cancel func(), err error) {
+	return n.p.PublishAfter(n.name(topic), delay, args...)
+}
+
+func (n *namespace) PublishIfSubscribed(topic string, build func() []any) (bool, error) {
+	return n.p.PublishIfSubscribed(n.name(topic), build)
+}
+
+func (n *namespace) PublishEvent(topic string, payload any) (eventID string, err error) {
+	return n.p.PublishEvent(n.name(topic), payload)
+}
+
+func (n *namespace) PublishWithID(topic, correlationID string, args ...any) error {
+	return n.p.PublishWithID(n.name(topic), correlationID, args...)
+}
+
+func (n *namespace) PublishAck(topic string, args ...any) (<-chan struct{}, error) {
+	return n.p.PublishAck(n.name(topic), args...)
+}
+
+func (n *namespace) Respond(topic string, handler func(...any) []any) error {
+	return n.p.Respond(n.name(topic), handler)
+}
+
+func (n *namespace) Request(topic string, timeout time.Duration, args ...any) ([]any, error) {
+	return n.p.Request(n.name(topic), timeout, args...)
+}
+
+func (n *namespace) RequestHedged(topic string, hedgeAfter time.Duration, timeout time.Duration, args ...any) ([]any, error) {
+	return n.p.RequestHedged(n.name(topic), hedgeAfter, timeout, args...)
+}
+
+// Topics returns the names of the namespace's open topics in sorted order, without the prefix.
+func (n *namespace) Topics() []string {
+	var names []string
+	for _, name := range n.p.Topics() {
+		if local, ok := n.local(name); ok {
+			names = append(names, local)
+		}
+	}
+	return names
+}
+
+func (n *namespace) HasTopic(topic string) bool {
+	return n.p.HasTopic(n.name(topic))
+}
+
+func (n *namespace) IsClosed(topic string) bool {
+	return n.p.IsClosed(n.name(topic))
+}
+
+func (n *namespace) HandlerStats(topic string) []HandlerTiming {
+	return n.p.HandlerStats(n.name(topic))
+}
+
+func (n *namespace) DroppedCount(topic string) uint64 {
+	return n.p.DroppedCount(n.name(topic))
+}
+
+// TotalDropped returns the number of discarded messages of the namespace's current topics.
+func (n *namespace) TotalDropped() uint64 {
+	var total uint64
+	for _, t := range n.topics() {
+		total += t.drops.Load()
+	}
+	return total
+}
+
+func (n *namespace) History(topic string, limit int) [][]any {
+	return n.p.History(n.name(topic), limit)
+}
+
+// Snapshot returns the number of handlers of each of the namespace's open topics.
+func (n *namespace) Snapshot() map[string]int {
+	snapshot := make(map[string]int)
+	for name, handlers := range n.p.Snapshot() {
+		if local, ok := n.local(name); ok {
+			snapshot[local] = handlers
+		}
+	}
+	return snapshot
+}
+
+// Range calls fn for each of the namespace's open topics until fn returns false.
+func (n *namespace) Range(fn func(topic string, subscriberCount int) bool) {
+	rangeSnapshot(n.Snapshot(), fn)
+}
+
+// Clone clones the whole PubSub and returns the same namespace of the clone.
+func (n *namespace) Clone() PubSub {
+	return &namespace{p: n.p.Clone().(*pubsub), prefix: n.prefix}
+}
+
+func (n *namespace) WaitForSubscribers(ctx context.Context, topic string, count int) error {
+	return n.p.WaitForSubscribers(ctx, n.name(topic), count)
+}
+
+func (n *namespace) Pause(topic string) error {
+	return n.p.Pause(n.name(topic))
+}
+
+func (n *namespace) Resume(topic string) error {
+	return n.p.Resume(n.name(topic))
+}
+
+func (n *namespace) CloseTopic(topic string) error {
+	return n.p.CloseTopic(n.name(topic))
+}
+
+func (n *namespace) CloseSubtree(prefix string) (int, error) {
+	return n.p.CloseSubtree(n.name(prefix))
+}
+
+func (n *namespace) DeclareTopic(topic string) error {
+	return n.p.DeclareTopic(n.name(topic))
+}
+
+// ExportTopics returns the names of the namespace's open and declared topics.
+func (n *namespace) ExportTopics() []string {
+	var names []string
+	for _, name := range n.p.ExportTopics() {
+		if local, ok := n.local(name); ok {
+			names = append(names, local)
+		}
+	}
+	return names
+}
+
+func (n *namespace) ImportTopics(topics []string) error {
+	return n.p.ImportTopics(n.names(topics))
+}
+
+func (n *namespace) Alias(from, to string) error {
+	return n.p.Alias(n.name(from), n.name(to))
+}
+
+func (n *namespace) RemoveAlias(from string) error {
+	return n.p.RemoveAlias(n.name(from))
+}
+
+// Shutdown closes the namespace's topics. The rest of the PubSub keeps running.
+func (n *namespace) Shutdown() error {
+	var errs []error
+	for _, t := range n.topics() {
+		if err := t.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Closed reports whether the PubSub the namespace belongs to has been shut down, which
+// Shutdown of the namespace doesn't do.
+func (n *namespace) Closed() bool {
+	return n.p.Closed()
+}
+
+// ShutdownExcept closes the namespace's topics except the given ones.
+func (n *namespace) ShutdownExcept(topics ...string) error {
+	keep := make(map[string]bool, len(topics))
+	for _, name := range n.names(topics) {
+		keep[name] = true
+	}
+	return closeExcept(n.topics(), keep)
+}
+
+// Reset closes and deletes the namespace's topics and forgets its aliases and declared
+// topics. The rest of the PubSub keeps running.
+func (n *namespace) Reset() error {
+	var errs []error
+	for _, t := range n.topics() {
+		if err := t.close(); err != nil {
+			errs = append(errs, err)
+		}
+		n.p.forget(t)
+	}
+	n.p.aliasesMu.Lock()
+	for from := range n.p.aliases {
+		if strings.HasPrefix(from, n.prefix) {
+			delete(n.p.aliases, from)
+		}
+	}
+	n.p.aliasesMu.Unlock()
+	n.p.declaredMu.Lock()
+	for topic := range n.p.declared {
+		if strings.HasPrefix(topic, n.prefix) {
+			delete(n.p.declared, topic)
+		}
+	}
+	n.p.declaredMu.Unlock()
+	return errors.Join(errs...)
+}
+
+// Drain waits until the messages queued for the namespace's topics have been delivered.
+func (n *namespace) Drain(ctx context.Context) error {
+	return n.p.drain(ctx, n.topics)
+}
+
+func (n *namespace) Do(msg Message) error {
+	return do(n, msg)
+}
+
+func (n *namespace) Run(ctx context.Context, commands <-chan Message) error {
+	return run(ctx, n, commands)
+}
+
+// Namespace returns a namespace nested in this one.
+func (n *namespace) Namespace(prefix string) PubSub {
+	return &namespace{p: n.p, prefix: n.name(prefix) + "."}
+}
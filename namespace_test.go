@@ -0,0 +1,128 @@
+package pubsub
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamespace(t *testing.T) {
+	ps := New()
+	billing := ps.Namespace("billing")
+	shipping := ps.Namespace("shipping")
+
+	var received []string
+	subscribe := func(ns PubSub, name string) {
+		if err := ns.Subscribe("orders", func(args ...any) {
+			received = append(received, name+":"+args[0].(string))
+		}); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+	subscribe(billing, "billing")
+	subscribe(shipping, "shipping")
+
+	// The namespaces are isolated from each other.
+	if err := billing.Publish("orders", "a"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	// Publishes to the fully-qualified name on the root reach the namespaced handler.
+	if err := ps.Publish("shipping.orders", "b"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	// The root's topic of the same name is a different topic.
+	if err := ps.Publish("orders", "c"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if want := []string{"billing:a", "shipping:b"}; !reflect.DeepEqual(received, want) {
+		t.Errorf("expected %v, got %v", want, received)
+	}
+
+	if topics := billing.Topics(); !reflect.DeepEqual(topics, []string{"orders"}) {
+		t.Errorf("expected the namespace's topics without the prefix, got %v", topics)
+	}
+	if topics := ps.Topics(); !reflect.DeepEqual(topics, []string{"billing.orders", "shipping.orders"}) {
+		t.Errorf("expected the fully-qualified topics on the root, got %v", topics)
+	}
+}
+
+func TestNamespaceShutdown(t *testing.T) {
+	ps := New()
+	billing := ps.Namespace("billing")
+	for _, topic := range []string{"billing", "billing.orders", "shipping.orders"} {
+		if err := ps.Subscribe(topic, func(args ...any) {}); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+	if err := billing.Subscribe("invoices", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := billing.Shutdown(); err != nil {
+		t.Fatalf("Shutdown returned an error: %s", err.Error())
+	}
+	// Only the namespace's topics are closed, not the topic named like the namespace.
+	want := []string{"billing", "shipping.orders"}
+	if topics := ps.Topics(); !reflect.DeepEqual(topics, want) {
+		t.Errorf("expected topics %v after the namespace's Shutdown, got %v", want, topics)
+	}
+}
+
+func TestNamespaceShutdownExcept(t *testing.T) {
+	ps := New()
+	billing := ps.Namespace("billing")
+	for _, topic := range []string{"billing.control", "billing.orders", "control"} {
+		if err := ps.Subscribe(topic, func(args ...any) {}); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	if err := billing.ShutdownExcept("control"); err != nil {
+		t.Fatalf("ShutdownExcept returned an error: %s", err.Error())
+	}
+	// Topics outside the namespace are left alone.
+	want := []string{"billing.control", "control"}
+	if topics := ps.Topics(); !reflect.DeepEqual(topics, want) {
+		t.Errorf("expected topics %v after the namespace's ShutdownExcept, got %v", want, topics)
+	}
+}
+
+func TestNamespaceSubscribeDefault(t *testing.T) {
+	ps := New()
+	billing := ps.Namespace("billing")
+	var topics []string
+	if _, err := billing.SubscribeDefault(func(topic string, args ...any) { topics = append(topics, topic) }); err != nil {
+		t.Fatalf("SubscribeDefault returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("shipping.orders"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if err := billing.Publish("invoices"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	// Only the namespace's topics reach it, with their names in the namespace.
+	if want := []string{"invoices"}; !reflect.DeepEqual(topics, want) {
+		t.Errorf("expected the default handler to receive %v, got %v", want, topics)
+	}
+}
+
+func TestNamespaceNested(t *testing.T) {
+	ps := New()
+	var topics []string
+	cancel, err := ps.Namespace("a").Namespace("b").SubscribeMerged([]string{"x", "y"}, func(topic string, args ...any) {
+		topics = append(topics, topic)
+	})
+	if err != nil {
+		t.Fatalf("SubscribeMerged returned an error: %s", err.Error())
+	}
+	defer cancel()
+
+	if err := ps.Publish("a.b.x"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if err := ps.Namespace("a").Publish("b.y"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if want := []string{"x", "y"}; !reflect.DeepEqual(topics, want) {
+		t.Errorf("expected the handler to see topics relative to its namespace %v, got %v", want, topics)
+	}
+}
@@ -0,0 +1,60 @@
+package pubsub
+
+import "context"
+
+// Next waits for the next message published to the topic and returns its args.
+// It subscribes a temporary handler that is removed again when Next returns,
+// including when ctx is done first, in which case the context's error is returned.
+func (p *pubsub) Next(ctx context.Context, topic string) ([]any, error) {
+	msgs := make(chan []any, 1)
+	cancel, err := p.subscribe(topic, &subscription{
+		handler: func(args ...any) {
+			msgs <- args
+		},
+		once: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	select {
+	case args := <-msgs:
+		return args, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// SelectNext waits for the next message published to any of the topics and returns it
+// with the name of the topic it was published to, like a select over channels. It adds
+// temporary handlers to all of the topics atomically and removes them again when it
+// returns, including when ctx is done first, in which case the context's error is
+// returned. Messages published after the first one are not consumed.
+func (p *pubsub) SelectNext(ctx context.Context, topics ...string) (topic string, args []any, err error) {
+	type message struct {
+		topic string
+		args  []any
+	}
+	msgs := make(chan message, 1)
+	cancel, err := p.subscribeAll(topics, func(topic string) func(...any) {
+		return func(args ...any) {
+			select {
+			case msgs <- message{topic, args}:
+			default:
+				// Another topic was first.
+			}
+		}
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	defer cancel()
+
+	select {
+	case msg := <-msgs:
+		return msg.topic, msg.args, nil
+	case <-ctx.Done():
+		return "", nil, ctx.Err()
+	}
+}
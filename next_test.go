@@ -0,0 +1,116 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	ps := New()
+	go func() {
+		if err := ps.WaitForSubscribers(context.Background(), "testTopic", 1); err != nil {
+			t.Errorf("WaitForSubscribers returned an error: %s", err.Error())
+		}
+		if err := ps.Publish("testTopic", "first"); err != nil {
+			t.Errorf("Publish returned an error: %s", err.Error())
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	args, err := ps.Next(ctx, "testTopic")
+	if err != nil {
+		t.Fatalf("Next returned an error: %s", err.Error())
+	}
+	if len(args) != 1 || args[0] != "first" {
+		t.Errorf("expected [first], got %v", args)
+	}
+	if n := ps.Snapshot()["testTopic"]; n != 0 {
+		t.Errorf("expected the temporary handler to be removed, got %d handlers", n)
+	}
+}
+
+func TestNextCancelled(t *testing.T) {
+	ps := New()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := ps.Next(ctx, "testTopic"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if n := ps.Snapshot()["testTopic"]; n != 0 {
+		t.Errorf("expected the temporary handler to be removed, got %d handlers", n)
+	}
+}
+
+func TestSelectNext(t *testing.T) {
+	ps := New()
+	go func() {
+		for _, topic := range []string{"first", "second"} {
+			if err := ps.WaitForSubscribers(context.Background(), topic, 1); err != nil {
+				t.Errorf("WaitForSubscribers returned an error: %s", err.Error())
+			}
+		}
+		if err := ps.Publish("second", "hello"); err != nil {
+			t.Errorf("Publish returned an error: %s", err.Error())
+		}
+		if err := ps.Publish("first", "late"); err != nil {
+			t.Errorf("Publish returned an error: %s", err.Error())
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	topic, args, err := ps.SelectNext(ctx, "first", "second")
+	if err != nil {
+		t.Fatalf("SelectNext returned an error: %s", err.Error())
+	}
+	if topic != "second" || len(args) != 1 || args[0] != "hello" {
+		t.Errorf("expected [hello] from second, got %v from %q", args, topic)
+	}
+	for _, topic := range []string{"first", "second"} {
+		if n := ps.Snapshot()[topic]; n != 0 {
+			t.Errorf("expected the temporary handler of %s to be removed, got %d handlers", topic, n)
+		}
+	}
+}
+
+func TestSelectNextCancelled(t *testing.T) {
+	ps := New()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, _, err := ps.SelectNext(ctx, "first", "second"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	for _, topic := range []string{"first", "second"} {
+		if n := ps.Snapshot()[topic]; n != 0 {
+			t.Errorf("expected the temporary handler of %s to be removed, got %d handlers", topic, n)
+		}
+	}
+}
+
+func TestSelectNextNamespace(t *testing.T) {
+	ps := New()
+	billing := ps.Namespace("billing")
+	go func() {
+		if err := ps.WaitForSubscribers(context.Background(), "billing.invoices", 1); err != nil {
+			t.Errorf("WaitForSubscribers returned an error: %s", err.Error())
+		}
+		if err := billing.Publish("invoices", 42); err != nil {
+			t.Errorf("Publish returned an error: %s", err.Error())
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	topic, args, err := billing.SelectNext(ctx, "orders", "invoices")
+	if err != nil {
+		t.Fatalf("SelectNext returned an error: %s", err.Error())
+	}
+	if topic != "invoices" || len(args) != 1 || args[0] != 42 {
+		t.Errorf("expected [42] from invoices, got %v from %q", args, topic)
+	}
+}
@@ -0,0 +1,399 @@
+package pubsub
+
+import "time"
+
+// Option configures a PubSub created by New.
+type Option func(*options)
+
+type options struct {
+	// shards is the number of stripes the topics map is split into.
+	shards int
+	// visibilityTimeout is how long a reliable subscriber has to acknowledge a message.
+	visibilityTimeout time.Duration
+	// maxAttempts is how many times a message is delivered to a reliable subscriber.
+	maxAttempts int
+	// deadLetterTopic receives messages that could not be delivered.
+	deadLetterTopic string
+	// pauseBuffer buffers messages published to paused topics instead of dropping them,
+	// up to pauseBufferLimit per topic.
+	pauseBuffer      bool
+	pauseBufferLimit int
+	// rateLimits holds the rate limit of each rate limited topic.
+	rateLimits    map[string]rateLimit
+	rateLimitMode RateLimitMode
+	// logger is nil unless logging is enabled.
+	logger Logger
+	// maxSubscribers limits the number of handlers per topic if positive.
+	maxSubscribers int
+	// globalOrder delivers all publishes from a single goroutine in publish order.
+	globalOrder     bool
+	globalOrderWait bool
+	// fairScheduling makes the global order dispatcher serve topics round-robin.
+	fairScheduling bool
+	// async queues published messages and delivers them from a goroutine per topic.
+	async       bool
+	asyncBuffer int
+	// handlerTiming records how long each handler takes to run.
+	handlerTiming bool
+	// topicTTL is how long a topic can be idle before it is removed, if positive.
+	topicTTL time.Duration
+	// backpressure is called when an asynchronous topic's queue fills up.
+	backpressure func(topic string, queueLen, capacity int)
+	// argCopy gives each handler its own copy of the args slice.
+	argCopy bool
+	// historySize is how many published messages each topic keeps for History, if positive.
+	historySize int
+	// strictTopics rejects invalid topic names and trims the valid ones.
+	strictTopics bool
+	// workers is how many goroutines call a message's handlers in parallel, if more than one.
+	workers int
+	// maxPublishDepth is how deeply handlers can publish to each other, if positive.
+	maxPublishDepth int
+	// systemTopic publishes lifecycle events to SystemTopic.
+	systemTopic bool
+	// overflowPolicy decides what happens to messages published to a full queue.
+	overflowPolicy OverflowPolicy
+	// onDrop, if set, is called with the messages dropped by the overflow policy.
+	onDrop func(topic string, args []any)
+	// clock is the source of time.
+	clock Clock
+	// recoverPanics recovers the panics of handlers even without a dead-letter topic,
+	// and panicPolicy decides what happens to the topic afterwards.
+	recoverPanics bool
+	panicPolicy   PanicPolicy
+	// explicitTopics requires topics to be declared before they are used.
+	explicitTopics bool
+	// slowHandler, if set, is called when a handler runs for longer than slowHandlerThreshold.
+	slowHandler          func(topic string, index int, elapsed time.Duration)
+	slowHandlerThreshold time.Duration
+	// metrics, if set, receives the PubSub's metrics.
+	metrics MetricsSink
+	// partitioners holds the partitioner of each partitioned topic.
+	partitioners map[string]func(args ...any) int
+	// deliveryGoroutine calls the handlers of synchronous topics on a new goroutine.
+	deliveryGoroutine bool
+	// errorTopic, if set, receives an Event for each handler that panicked or failed.
+	errorTopic string
+}
+
+func defaultOptions() options {
+	return options{
+		shards:            32,
+		visibilityTimeout: 30 * time.Second,
+		maxAttempts:       3,
+		maxPublishDepth:   64,
+		pauseBufferLimit:  1024,
+		clock:             realClock{},
+	}
+}
+
+// WithRedelivery sets how long a reliable subscriber has to acknowledge a message before
+// it is redelivered, and how many times it is delivered in total before it is dead-lettered.
+// The defaults are 30 seconds and 3 attempts.
+func WithRedelivery(visibilityTimeout time.Duration, maxAttempts int) Option {
+	return func(o *options) {
+		o.visibilityTimeout = visibilityTimeout
+		o.maxAttempts = maxAttempts
+	}
+}
+
+// WithDeadLetter sets the topic that messages which could not be delivered are published to.
+// This includes messages whose handler panicked, which are recovered when a dead-letter topic is set,
+// and messages whose handler subscribed with SubscribeErr returned an error.
+// The original args are published followed by an error describing the failure.
+// Without a dead-letter topic such messages are dropped and panics are not recovered,
+// unless a panic policy is set with WithPanicPolicy.
+func WithDeadLetter(topic string) Option {
+	return func(o *options) {
+		o.deadLetterTopic = topic
+	}
+}
+
+// WithPauseBuffer makes paused topics buffer published messages and deliver them on Resume,
+// instead of dropping them. Each topic buffers up to 1024 messages by default, which
+// WithPauseBufferLimit changes; messages published to a full buffer are dropped and
+// counted like those dropped by the overflow policy.
+func WithPauseBuffer() Option {
+	return func(o *options) {
+		o.pauseBuffer = true
+	}
+}
+
+// WithPauseBufferLimit sets how many messages a paused topic buffers if WithPauseBuffer is
+// set. Zero or less removes the limit.
+func WithPauseBufferLimit(n int) Option {
+	return func(o *options) {
+		o.pauseBufferLimit = n
+	}
+}
+
+// WithRateLimit limits delivery on the topic to ratePerSec messages per second,
+// allowing bursts of up to burst messages. Other topics are not affected.
+// What happens to messages over the limit is set by WithRateLimitMode. ratePerSec must be
+// positive and burst at least 1; publishing to a topic whose rate limit isn't valid fails
+// with ErrInvalidRateLimit.
+func WithRateLimit(topic string, ratePerSec float64, burst int) Option {
+	return func(o *options) {
+		if o.rateLimits == nil {
+			o.rateLimits = make(map[string]rateLimit)
+		}
+		o.rateLimits[topic] = rateLimit{ratePerSec: ratePerSec, burst: burst}
+	}
+}
+
+// WithRateLimitMode sets whether Publish blocks or drops messages when a topic's
+// rate limit is exceeded. The default is RateLimitBlock.
+func WithRateLimitMode(mode RateLimitMode) Option {
+	return func(o *options) {
+		o.rateLimitMode = mode
+	}
+}
+
+// WithShards sets the number of stripes the topics map is split into, each with its own lock.
+// More shards let operations on different topics proceed in parallel. The default is 32;
+// values below 1 are treated as 1.
+func WithShards(n int) Option {
+	return func(o *options) {
+		if n < 1 {
+			n = 1
+		}
+		o.shards = n
+	}
+}
+
+// WithLogger makes the PubSub log subscribe, unsubscribe, publish and close events
+// at debug level. Nothing is logged by default.
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithMaxSubscribers limits the number of handlers a topic can have to n.
+// Subscribing to a topic that already has n handlers returns ErrTooManySubscribers.
+// This catches handler leaks such as subscribing in a loop. There is no limit by default.
+func WithMaxSubscribers(n int) Option {
+	return func(o *options) {
+		o.maxSubscribers = n
+	}
+}
+
+// WithGlobalOrder makes the PubSub deliver all publishes, across all topics, one at a time
+// and in the order they were made, from a single goroutine. Publish and TryPublish enqueue
+// the message and, if wait is true, block until its handlers have run.
+// With wait set, handlers must not publish, as they would wait for themselves.
+func WithGlobalOrder(wait bool) Option {
+	return func(o *options) {
+		o.globalOrder = true
+		o.globalOrderWait = wait
+	}
+}
+
+// WithFairScheduling makes a PubSub created with WithGlobalOrder take turns between the
+// topics with pending messages instead of delivering all publishes in the order they were
+// made, so a topic flooded with messages can't hold back the others. Messages of the same
+// topic are still delivered in the order they were published. It has no effect without
+// WithGlobalOrder.
+func WithFairScheduling() Option {
+	return func(o *options) {
+		o.fairScheduling = true
+	}
+}
+
+// WithAsync makes the PubSub deliver messages asynchronously: each topic queues up to
+// buffer published messages, which a goroutine per topic delivers in order.
+// Publish blocks while the topic's queue is full, whereas TryPublish fails fast with
+// ErrWouldBlock; WithOverflow can make both drop messages instead. Messages still queued
+// when a topic is closed are dropped, and closing it returns ErrUndelivered.
+func WithAsync(buffer int) Option {
+	return func(o *options) {
+		o.async = true
+		o.asyncBuffer = buffer
+	}
+}
+
+// WithHandlerTiming makes the PubSub record how long each handler takes to run,
+// which HandlerStats reports. Timing is disabled by default and costs nothing then.
+func WithHandlerTiming() Option {
+	return func(o *options) {
+		o.handlerTiming = true
+	}
+}
+
+// WithTopicTTL makes the PubSub remove topics that have no handlers and haven't been
+// subscribed or published to for d. A background goroutine checks for idle topics every d/2
+// until Shutdown. Paused topics are never removed. Topics don't expire by default.
+func WithTopicTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.topicTTL = d
+	}
+}
+
+// WithBackpressure sets a callback that is called when a publish finds an asynchronous
+// topic's queue at or above three quarters of its capacity, so producers can slow down.
+// It is called on the publishing goroutine, at most every 100ms per topic.
+// See WithAsync.
+func WithBackpressure(cb func(topic string, queueLen, capacity int)) Option {
+	return func(o *options) {
+		o.backpressure = cb
+	}
+}
+
+// WithArgCopy gives each handler its own shallow copy of the published args, so a handler
+// that modifies the slice doesn't affect the handlers called after it. Values the args
+// point to are still shared; copying those is up to the caller.
+func WithArgCopy() Option {
+	return func(o *options) {
+		o.argCopy = true
+	}
+}
+
+// WithHistory makes each topic keep the last size messages published to it, which
+// History returns. With history enabled, publishing to a topic that doesn't exist yet
+// creates it, so its messages are recorded even before anyone subscribes.
+func WithHistory(size int) Option {
+	return func(o *options) {
+		o.historySize = size
+	}
+}
+
+// WithStrictTopics makes the PubSub trim whitespace around topic names and reject names
+// that are empty or contain control characters with ErrInvalidTopic when subscribing,
+// publishing or closing a topic. By default any string, including "", is a topic name.
+func WithStrictTopics() Option {
+	return func(o *options) {
+		o.strictTopics = true
+	}
+}
+
+// WithWorkers makes the PubSub call the handlers of each message from up to n goroutines
+// in parallel, which speeds up fanning out to many CPU-bound handlers. Publish still returns
+// only after all handlers finished, but the order handlers are called in is not guaranteed,
+// and a handler that panics crashes the program unless there is a dead-letter topic.
+// By default handlers are called one after the other on the publishing goroutine.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// WithMaxPublishDepth sets how deeply nested publishes from handlers can be before a publish
+// fails with ErrPublishLoop, which breaks loops of handlers publishing to each other's topics.
+// The default is 64. Zero or less disables the check. Deliveries handed off to the goroutines
+// of WithWorkers or WithDeliveryGoroutine continue at the depth of the publish, but those
+// queued by WithAsync start over.
+func WithMaxPublishDepth(n int) Option {
+	return func(o *options) {
+		o.maxPublishDepth = n
+	}
+}
+
+// WithOverflow sets what publishing to an asynchronous topic whose queue is full does,
+// which bounds the memory used by a topic under sustained overload. The default is
+// OverflowBlock. If onDrop isn't nil, it is called on the publishing goroutine with each
+// message the policy drops, and with those dropped because a pause buffer is full.
+// See WithAsync.
+func WithOverflow(policy OverflowPolicy, onDrop func(topic string, args []any)) Option {
+	return func(o *options) {
+		o.overflowPolicy = policy
+		o.onDrop = onDrop
+	}
+}
+
+// WithPanicPolicy makes the PubSub recover panics of handlers, with or without a dead-letter
+// topic, and sets what happens to the topic afterwards, which can quarantine a failing
+// handler automatically. The default policy, which also applies when panics are recovered
+// because of WithDeadLetter, is PanicContinue.
+func WithPanicPolicy(policy PanicPolicy) Option {
+	return func(o *options) {
+		o.recoverPanics = true
+		o.panicPolicy = policy
+	}
+}
+
+// WithExplicitTopics requires topics to be declared with DeclareTopic before they can be
+// subscribed or published to, which otherwise returns ErrUnknownTopic. This catches
+// misspelled topic names, which would silently create a new topic by default.
+func WithExplicitTopics() Option {
+	return func(o *options) {
+		o.explicitTopics = true
+	}
+}
+
+// WithSlowHandlerWarning makes the PubSub call cb when a handler has been running for
+// longer than threshold, with the topic, the handler's index among the topic's handlers
+// and how long it has been running, which helps finding stuck consumers. cb is called
+// from a watchdog goroutine while the handler keeps running; it is diagnostic only.
+func WithSlowHandlerWarning(threshold time.Duration, cb func(topic string, index int, elapsed time.Duration)) Option {
+	return func(o *options) {
+		o.slowHandlerThreshold = threshold
+		o.slowHandler = cb
+	}
+}
+
+// WithMetrics makes the PubSub report its counters of publishes, deliveries, drops and
+// handler errors, and the number of open topics, to sink. See MetricsSink for the metrics.
+func WithMetrics(sink MetricsSink) Option {
+	return func(o *options) {
+		o.metrics = sink
+	}
+}
+
+// WithPartitioner sets the function that assigns the messages published to the topic to
+// partitions, whose handlers are added with SubscribePartition. fn is called once per
+// message delivered to handlers, which it should be cheap for, and must return the same
+// partition for the same args to keep messages with the same key on the same handler.
+func WithPartitioner(topic string, fn func(args ...any) int) Option {
+	return func(o *options) {
+		if o.partitioners == nil {
+			o.partitioners = make(map[string]func(args ...any) int)
+		}
+		o.partitioners[topic] = fn
+	}
+}
+
+// WithDeliveryGoroutine makes Publish call the handlers of a synchronous PubSub on a new
+// goroutine for each message, but still return only after all of them finished. Unlike
+// inline delivery, handlers then don't share the stack of the publishing goroutine, its OS
+// thread locked with runtime.LockOSThread or anything else tied to it. The caller is blocked
+// while they run, though, so a handler waiting for a lock the caller holds deadlocks all
+// the same. A handler's panic is raised again on the publishing goroutine, without the
+// handler's stack. Nested publishes from handlers still count towards the depth set by
+// WithMaxPublishDepth, so a handler publishing to its own topic fails with ErrPublishLoop
+// instead of starting goroutines without end. Asynchronous topics aren't affected.
+func WithDeliveryGoroutine() Option {
+	return func(o *options) {
+		o.deliveryGoroutine = true
+	}
+}
+
+// WithErrorTopic makes the PubSub publish an Event to topic whenever a handler panics or
+// a handler added with SubscribeErr returns an error, so failures can be consumed like
+// any other message. The Event's Payload is a *HandlerError with the failed handler's
+// topic, index, error and the message's args; the error of a panic wraps ErrHandlerPanic.
+// Panics are recovered when an error topic is set. Failures of the error topic's own
+// handlers are not published, so they can't loop.
+func WithErrorTopic(topic string) Option {
+	return func(o *options) {
+		o.errorTopic = topic
+	}
+}
+
+// WithClock sets the source of time of the PubSub, which is the real clock by default.
+// Tests can pass a Clock they control to trigger delayed publishes, redeliveries, rate
+// limits and topic expiry deterministically instead of sleeping.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithSystemTopic makes the PubSub publish an Event to SystemTopic whenever a topic is
+// created or closed or a handler is added to or removed from a topic, so subscribers of
+// SystemTopic can audit the bus's activity. Events are published synchronously by the
+// goroutine that made the change, after the change, with the topic's lock released.
+func WithSystemTopic() Option {
+	return func(o *options) {
+		o.systemTopic = true
+	}
+}
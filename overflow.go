@@ -0,0 +1,62 @@
+package pubsub
+
+// OverflowPolicy determines what publishing to an asynchronous topic does when the
+// topic's queue is full. See WithAsync and WithOverflow.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes Publish wait until there is room in the queue,
+	// while TryPublish returns ErrWouldBlock.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest evicts the oldest queued message to make room for the new one.
+	OverflowDropOldest
+	// OverflowDropNewest drops the new message and keeps the queued ones.
+	OverflowDropNewest
+)
+
+// overflow handles a message published while the topic's queue is full, according to
+// the overflow policy. It reports whether the message was dealt with; if not, the caller
+// should fall back to blocking.
+func (t *topic) overflow(m queued) bool {
+	switch t.p.opts.overflowPolicy {
+	case OverflowDropNewest:
+		t.dropped(m)
+		return true
+	case OverflowDropOldest:
+		if cap(t.queue) == 0 {
+			// An unbuffered queue holds no message to evict.
+			t.dropped(m)
+			return true
+		}
+		for {
+			select {
+			case oldest := <-t.queue:
+				t.dropped(oldest)
+			default:
+				// The worker emptied the queue in the meantime.
+			}
+			select {
+			case t.queue <- m:
+				return true
+			case <-t.done:
+				t.unqueue(1)
+				m.done()
+				return true
+			default:
+				// Another publisher took the room; evict again.
+			}
+		}
+	}
+	return false
+}
+
+// dropped calls the drop callback, if any, with a message that was dropped because the
+// topic's queue was full.
+func (t *topic) dropped(m queued) {
+	t.countDrops(1)
+	t.unqueue(1)
+	if onDrop := t.p.opts.onDrop; onDrop != nil {
+		onDrop(t.name, m.args)
+	}
+	m.done()
+}
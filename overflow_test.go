@@ -0,0 +1,99 @@
+package pubsub
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testOverflow publishes five messages to an asynchronous topic with a queue of two while
+// its handler is busy with the first, then returns the delivered and the dropped messages.
+func testOverflow(t *testing.T, policy OverflowPolicy) (received, dropped []any) {
+	t.Helper()
+	var mu sync.Mutex
+	ps := New(WithAsync(2), WithOverflow(policy, func(topic string, args []any) {
+		mu.Lock()
+		dropped = append(dropped, args[0])
+		mu.Unlock()
+	}))
+	defer ps.Shutdown()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	err := ps.Subscribe("testTopic", func(args ...any) {
+		if args[0] == 1 {
+			close(started)
+			<-release
+		}
+		mu.Lock()
+		received = append(received, args[0])
+		n := len(received)
+		mu.Unlock()
+		if n == 3 && policy != OverflowBlock || n == 5 {
+			close(done)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("testTopic", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	<-started
+	if policy == OverflowBlock {
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			close(release)
+		}()
+	}
+	for i := 2; i <= 5; i++ {
+		if err := ps.Publish("testTopic", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if policy != OverflowBlock {
+		close(release)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("messages were not delivered")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return received, dropped
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	received, dropped := testOverflow(t, OverflowDropOldest)
+	if want := []any{1, 4, 5}; !reflect.DeepEqual(received, want) {
+		t.Errorf("expected %v to be delivered, got %v", want, received)
+	}
+	if want := []any{2, 3}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("expected the oldest %v to be dropped, got %v", want, dropped)
+	}
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	received, dropped := testOverflow(t, OverflowDropNewest)
+	if want := []any{1, 2, 3}; !reflect.DeepEqual(received, want) {
+		t.Errorf("expected %v to be delivered, got %v", want, received)
+	}
+	if want := []any{4, 5}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("expected the newest %v to be dropped, got %v", want, dropped)
+	}
+}
+
+func TestOverflowBlock(t *testing.T) {
+	received, dropped := testOverflow(t, OverflowBlock)
+	if want := []any{1, 2, 3, 4, 5}; !reflect.DeepEqual(received, want) {
+		t.Errorf("expected %v to be delivered, got %v", want, received)
+	}
+	if len(dropped) != 0 {
+		t.Errorf("expected nothing to be dropped, got %v", dropped)
+	}
+}
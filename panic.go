@@ -0,0 +1,50 @@
+package pubsub
+
+import "fmt"
+
+// PanicPolicy determines what happens to a topic after one of its handlers panicked.
+// See WithPanicPolicy.
+type PanicPolicy int
+
+const (
+	// PanicContinue recovers the panic and keeps the handler.
+	PanicContinue PanicPolicy = iota
+	// PanicRemoveHandler recovers the panic and removes the handler that panicked.
+	PanicRemoveHandler
+	// PanicCloseTopic recovers the panic and closes the topic.
+	PanicCloseTopic
+)
+
+// recovers reports whether panics of handlers are recovered.
+func (p *pubsub) recovers() bool {
+	return p.opts.deadLetterTopic != "" || p.opts.errorTopic != "" || p.opts.recoverPanics
+}
+
+// panicked handles the panic r of the handler of sub, the handler at index, which was
+// called with args. The message is dead-lettered, the panic reported to the error topic
+// and the panic policy applied.
+func (t *topic) panicked(index int, sub *subscription, args []any, r any) {
+	t.p.count(MetricErrors, t.name)
+	err := fmt.Errorf("%w on topic %q: %v", ErrHandlerPanic, t.name, r)
+	if t.name != t.p.opts.deadLetterTopic {
+		t.p.deadLetter(args, err)
+	}
+	t.p.failed(&HandlerError{Topic: t.name, Index: index, Err: err, Args: args})
+	switch t.p.opts.panicPolicy {
+	case PanicRemoveHandler:
+		t.remove(sub)
+	case PanicCloseTopic:
+		_ = t.close()
+	}
+}
+
+// defaultPanicked handles the panic r of the default handler at index, which was called
+// with a message published to the topic. Like the panic of a topic's handler, the message
+// is dead-lettered and the panic reported to the error topic; the panic policy only
+// applies to topics.
+func (p *pubsub) defaultPanicked(index int, topic string, args []any, r any) {
+	p.count(MetricErrors, topic)
+	err := fmt.Errorf("%w in default handler for topic %q: %v", ErrHandlerPanic, topic, r)
+	p.deadLetter(args, err)
+	p.failed(&HandlerError{Topic: topic, Index: index, Err: err, Args: args})
+}
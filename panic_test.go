@@ -0,0 +1,98 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+)
+
+func subscribePanicking(t *testing.T, ps PubSub) (calls *int) {
+	calls = new(int)
+	err := ps.Subscribe("testTopic", func(args ...any) {
+		panic("boom")
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	err = ps.Subscribe("testTopic", func(args ...any) {
+		*calls++
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	return calls
+}
+
+func TestPanicContinue(t *testing.T) {
+	ps := New(WithPanicPolicy(PanicContinue))
+	calls := subscribePanicking(t, ps)
+
+	for i := 0; i < 2; i++ {
+		if err := ps.Publish("testTopic"); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+
+	if *calls != 2 {
+		t.Fatalf("expected the other handler to be called twice, got %d", *calls)
+	}
+	if n := ps.Snapshot()["testTopic"]; n != 2 {
+		t.Fatalf("expected the panicking handler to be kept, got %d handlers", n)
+	}
+}
+
+func TestPanicRemoveHandler(t *testing.T) {
+	ps := New(WithPanicPolicy(PanicRemoveHandler))
+	calls := subscribePanicking(t, ps)
+
+	for i := 0; i < 2; i++ {
+		if err := ps.Publish("testTopic"); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+
+	if *calls != 2 {
+		t.Fatalf("expected the other handler to be called twice, got %d", *calls)
+	}
+	if n := ps.Snapshot()["testTopic"]; n != 1 {
+		t.Fatalf("expected only the panicking handler to be removed, got %d handlers", n)
+	}
+}
+
+func TestPanicCloseTopic(t *testing.T) {
+	ps := New(WithPanicPolicy(PanicCloseTopic))
+	calls := subscribePanicking(t, ps)
+
+	if err := ps.Publish("testTopic"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+
+	if *calls != 1 {
+		t.Fatalf("expected the message to reach the other handler, got %d calls", *calls)
+	}
+	if !ps.IsClosed("testTopic") {
+		t.Fatal("expected the topic to be closed")
+	}
+}
+
+func TestPanicPolicyWithDeadLetter(t *testing.T) {
+	ps := New(WithDeadLetter("dead"), WithPanicPolicy(PanicRemoveHandler))
+	var dead []any
+	err := ps.Subscribe("dead", func(args ...any) {
+		dead = args
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	subscribePanicking(t, ps)
+
+	if err := ps.Publish("testTopic", "payload"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+
+	if len(dead) != 2 || dead[0] != "payload" || !errors.Is(dead[1].(error), ErrHandlerPanic) {
+		t.Fatalf("expected the payload followed by ErrHandlerPanic, got %v", dead)
+	}
+	if n := ps.Snapshot()["testTopic"]; n != 1 {
+		t.Fatalf("expected the panicking handler to be removed, got %d handlers", n)
+	}
+}
@@ -0,0 +1,42 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoPartitioner is returned by SubscribePartition for a topic without a partitioner.
+var ErrNoPartitioner = errors.New("pubsub: no partitioner for topic")
+
+// SubscribePartition adds a handler to the topic that only receives the messages the
+// topic's partitioner, set with WithPartitioner, assigns to partition. Messages with the
+// same key then always reach the same handler, in the order they were published. Messages
+// assigned to a partition without a handler are dropped. Subscribing to a topic without a
+// partitioner returns ErrNoPartitioner.
+func (p *pubsub) SubscribePartition(topic string, partition int, handler func(...any)) error {
+	name, err := p.topicName(topic)
+	if err != nil {
+		return err
+	}
+	if _, ok := p.opts.partitioners[name]; !ok {
+		return fmt.Errorf("%w: %q", ErrNoPartitioner, name)
+	}
+	_, err = p.subscribe(name, &subscription{handler: handler, partitioned: true, partition: partition})
+	return err
+}
+
+// normalizePartitioners keys the partitioners set with WithPartitioner by the normalized
+// names of their topics, which is how topics look them up. Partitioners of invalid topic
+// names are dropped, as nothing can be published to those.
+func (p *pubsub) normalizePartitioners() {
+	if !p.opts.strictTopics || len(p.opts.partitioners) == 0 {
+		return
+	}
+	partitioners := make(map[string]func(args ...any) int, len(p.opts.partitioners))
+	for topic, fn := range p.opts.partitioners {
+		if name, err := p.topicName(topic); err == nil {
+			partitioners[name] = fn
+		}
+	}
+	p.opts.partitioners = partitioners
+}
@@ -0,0 +1,140 @@
+package pubsub
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func parity(args ...any) int {
+	return args[0].(int) % 2
+}
+
+func TestSubscribePartition(t *testing.T) {
+	ps := New(WithPartitioner("numbers", parity))
+	var even, odd []int
+	if err := ps.SubscribePartition("numbers", 0, func(args ...any) { even = append(even, args[0].(int)) }); err != nil {
+		t.Fatalf("SubscribePartition returned an error: %s", err.Error())
+	}
+	if err := ps.SubscribePartition("numbers", 1, func(args ...any) { odd = append(odd, args[0].(int)) }); err != nil {
+		t.Fatalf("SubscribePartition returned an error: %s", err.Error())
+	}
+
+	for i := 0; i < 6; i++ {
+		if err := ps.Publish("numbers", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if want := []int{0, 2, 4}; !reflect.DeepEqual(even, want) {
+		t.Errorf("expected partition 0 to receive %v, got %v", want, even)
+	}
+	if want := []int{1, 3, 5}; !reflect.DeepEqual(odd, want) {
+		t.Errorf("expected partition 1 to receive %v, got %v", want, odd)
+	}
+}
+
+func TestSubscribePartitionAsync(t *testing.T) {
+	ps := New(WithAsync(16), WithPartitioner("numbers", parity))
+	defer ps.Shutdown()
+
+	const total = 100
+	var mu sync.Mutex
+	got := make(map[int][]int)
+	var wg sync.WaitGroup
+	wg.Add(total)
+	for partition := 0; partition < 2; partition++ {
+		partition := partition
+		err := ps.SubscribePartition("numbers", partition, func(args ...any) {
+			mu.Lock()
+			got[partition] = append(got[partition], args[0].(int))
+			mu.Unlock()
+			wg.Done()
+		})
+		if err != nil {
+			t.Fatalf("SubscribePartition returned an error: %s", err.Error())
+		}
+	}
+
+	for i := 0; i < total; i++ {
+		if err := ps.Publish("numbers", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("not all messages were delivered")
+	}
+
+	// Each partition receives its messages in publish order.
+	for partition, numbers := range got {
+		for i, n := range numbers {
+			if n%2 != partition || (i > 0 && n <= numbers[i-1]) {
+				t.Fatalf("partition %d received %v", partition, numbers)
+			}
+		}
+	}
+}
+
+func TestSubscribePartitionNoPartitioner(t *testing.T) {
+	ps := New(WithPartitioner("numbers", parity))
+	err := ps.SubscribePartition("other", 0, func(args ...any) {})
+	if !errors.Is(err, ErrNoPartitioner) {
+		t.Fatalf("expected ErrNoPartitioner, got %v", err)
+	}
+	if err := ps.SubscribePartition("numbers", 0, nil); !errors.Is(err, ErrNilHandler) {
+		t.Fatalf("expected ErrNilHandler, got %v", err)
+	}
+}
+
+func TestSubscribePartitionOncePerMessage(t *testing.T) {
+	// A partitioner that assigns each call to the next partition would deliver a message
+	// to no handler or to several if it were asked once per handler.
+	var calls int
+	roundRobin := func(args ...any) int {
+		calls++
+		return calls % 2
+	}
+	ps := New(WithPartitioner("numbers", roundRobin))
+	var received int
+	for partition := 0; partition < 2; partition++ {
+		if err := ps.SubscribePartition("numbers", partition, func(args ...any) { received++ }); err != nil {
+			t.Fatalf("SubscribePartition returned an error: %s", err.Error())
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		if err := ps.Publish("numbers", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if calls != 4 {
+		t.Errorf("expected the partitioner to be called once per message, got %d calls", calls)
+	}
+	if received != 4 {
+		t.Errorf("expected each message to reach exactly one handler, got %d deliveries", received)
+	}
+}
+
+func TestSubscribePartitionStrictTopics(t *testing.T) {
+	ps := New(WithStrictTopics(), WithPartitioner(" numbers ", parity))
+	var odd []int
+	if err := ps.SubscribePartition("numbers", 1, func(args ...any) { odd = append(odd, args[0].(int)) }); err != nil {
+		t.Fatalf("SubscribePartition returned an error: %s", err.Error())
+	}
+	for i := 0; i < 4; i++ {
+		if err := ps.Publish("numbers ", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if want := []int{1, 3}; !reflect.DeepEqual(odd, want) {
+		t.Errorf("expected partition 1 to receive %v, got %v", want, odd)
+	}
+}
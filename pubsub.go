@@ -1,5 +1,18 @@
 package pubsub
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 type Operation int
 
 const (
@@ -14,8 +27,39 @@ const (
 	Shutdown
 )
 
+var operationStrings = [...]string{
+	Subscribe:         "Subscribe",
+	SubscribeOnce:     "SubscribeOnce",
+	SubscribeOnceEach: "SubscribeOnceEach",
+	Publish:           "Publish",
+	TryPublish:        "TryPublish",
+	Unsubscribe:       "Unsubscribe",
+	UnsubscribeAll:    "UnsubscribeAll",
+	CloseTopic:        "CloseTopic",
+	Shutdown:          "Shutdown",
+}
+
+// String returns the name of the operation, e.g. "Publish".
+func (o Operation) String() string {
+	if o < 0 || int(o) >= len(operationStrings) {
+		return "Operation(" + strconv.Itoa(int(o)) + ")"
+	}
+	return operationStrings[o]
+}
+
+// ParseOperation returns the operation with the given name, as returned by String.
+func ParseOperation(s string) (Operation, error) {
+	for op, name := range operationStrings {
+		if name == s {
+			return Operation(op), nil
+		}
+	}
+	return 0, fmt.Errorf("pubsub: unknown operation %q", s)
+}
+
 // Message is a message sent to a pubsub instance.
 // It contains the operation to perform and the arguments to pass to the operation.
+// See PubSub.Do for the arguments each operation expects.
 type Message struct {
 	Topic     string
 	Operation Operation
@@ -23,184 +67,1545 @@ type Message struct {
 }
 
 // Subscriber is the interface that wraps the Subscribe, SubscribeOnce, SubscribeOnceEach, Unsubscribe and UnsubscribeAll methods.
-// Subscribe adds a handler to the topic.
+// Subscribe adds a handler to the topic. Subscribing to a closed topic returns ErrTopicClosed
+// and subscribing a nil handler returns ErrNilHandler.
 // SubscribeOnce adds a handler to the topic and removes it after the first call.
 // SubscribeOnceEach adds a handler to the topic and removes it after the first call for each handler.
-// Unsubscribe removes all handlers from the topic.
-// UnsubscribeAll removes all handlers from all topics
+// SubscribeOnceEachKeyed is like SubscribeOnceEach, but subscribing a key again replaces its handler.
+// SubscribeWithClose adds a handler to the topic and calls a function once the handler is removed.
+// SubscribeStateful2 delivers a snapshot of the current state to the handler and then adds it to the topic.
+// SubscribeKeyed adds a handler that processes messages with the same key in order and different keys in parallel.
+// SubscribeReliable adds a handler that must acknowledge each message and is redelivered messages it doesn't.
+// SubscribeFiltered adds a handler that is only called for messages matching a filter.
+// SubscribeAffine adds a handler to the topic that is registered only on the topic's shard.
+// SubscribePartition adds a handler that only receives the messages assigned to its partition.
+// SubscribeErr adds a handler that returns an error, which TryPublish reports.
+// SubscribeDefault adds a handler that receives the messages published to topics without handlers.
+// SubscribeWithRetry adds a handler that returns an error and is retried until it succeeds.
+// SubscribeMany adds a handler to several topics at once.
+// SubscribeMerged adds a handler to several topics at once and tells it which topic each message came from.
+// SubscribeWithID adds a handler to the topic and returns an ID that identifies it.
+// SubscribeUntil adds a handler to the topic that is removed when a context is done.
+// SubscribeEvents adds a handler that receives the Events published with PublishEvent.
+// SubscribeCoalesced adds a handler that is only called with the last message of each burst.
+// SubscribeBatch adds a handler that receives the topic's messages in batches.
+// UnsubscribeByID removes the handler with the given ID from the topic.
+// UnsubscribeHandler removes a handler from the topic by comparing funcs.
+// Next waits for the next message published to the topic.
+// SelectNext waits for the next message published to any of several topics.
+// Unsubscribe removes all handlers from the topic and closes it.
+// UnsubscribeAll removes all handlers from all topics and closes them.
+//
+// Handlers of a topic are called in the order they were subscribed, on every publish,
+// unless the PubSub calls them in parallel (see WithWorkers).
+// Removing a handler doesn't change the relative order of the others.
 type Subscriber interface {
 	Subscribe(topic string, handler func(...any)) error
-	SubscribeOnce(topic string, handler func(...any)) error
-	SubscribeOnceEach(topic string, handler func(...any)) error
+	SubscribeOnce(topic string, handler func(...any)) (cancel func(), err error)
+	SubscribeOnceEach(topic string, handler func(...any)) (cancel func(), err error)
+	SubscribeOnceEachKeyed(topic, key string, handler func(...any)) error
+	SubscribeWithClose(topic string, onMsg func(...any), onClose func()) (cancel func(), err error)
+	SubscribeStateful2(topic string, snapshot func() []any, handler func(...any)) error
+	SubscribeKeyed(topic string, keyFn func(args ...any) string, concurrency int, handler func(...any)) error
+	SubscribeReliable(topic string, handler func(args []any, ack func())) error
+	SubscribeFiltered(topic string, filter func(args ...any) bool, handler func(...any)) (cancel func(), err error)
+	SubscribeAffine(topic string, handler func(...any)) error
+	SubscribePartition(topic string, partition int, handler func(...any)) error
+	SubscribeErr(topic string, handler func(...any) error) (cancel func(), err error)
+	SubscribeDefault(handler func(topic string, args ...any)) (cancel func(), err error)
+	SubscribeWithRetry(topic string, attempts int, backoff time.Duration, handler func(...any) error) (cancel func(), err error)
+	SubscribeMany(topics []string, handler func(...any)) (cancel func(), err error)
+	SubscribeMerged(topics []string, handler func(topic string, args ...any)) (cancel func(), err error)
+	SubscribeWithID(topic string, handler func(...any)) (SubscriptionID, error)
+	SubscribeUntil(ctx context.Context, topic string, handler func(...any)) error
+	SubscribeEvents(topic string, handler func(Event)) (cancel func(), err error)
+	SubscribeCoalesced(topic string, window time.Duration, handler func(lastArgs ...any)) (cancel func(), err error)
+	SubscribeBatch(topic string, maxBatch int, maxWait time.Duration, handler func(batch [][]any)) (cancel func(), err error)
 	Unsubscribe(topic string) error
+	UnsubscribeByID(topic string, id SubscriptionID) error
+	UnsubscribeHandler(topic string, handler func(...any)) error
+	Next(ctx context.Context, topic string) ([]any, error)
+	SelectNext(ctx context.Context, topics ...string) (topic string, args []any, err error)
 	UnsubscribeAll() error
 }
 
-// Publisher is the interface that wraps the Publish and TryPublish methods.
+// Publisher is the interface that wraps the Publish, TryPublish, PublishWait, PublishMany, PublishAfter, PublishIfSubscribed, PublishEvent, PublishWithID and PublishAck methods.
 // Publish calls all handlers for the topic.
-// TryPublish calls all handlers for the topic and returns the first error.
+// TryPublish calls all handlers for the topic and returns the errors of those that failed.
+// On an asynchronous PubSub (see WithAsync), Publish blocks until the message is queued,
+// while TryPublish returns ErrWouldBlock instead of blocking when the queue is full.
+// PublishWait is like Publish, but stops waiting for room in the queue when a context is done.
+// PublishMany calls all handlers for each of the topics.
+// PublishAfter calls all handlers for the topic after a delay.
+// PublishIfSubscribed builds and publishes a message only if the topic has handlers.
+// PublishEvent publishes a payload wrapped in an Event with a unique ID and timestamp.
+// PublishWithID publishes a message wrapped in an Event that carries a correlation ID.
+// PublishAck publishes a message and returns a channel closed once its handlers have finished.
 type Publisher interface {
 	Publish(topic string, args ...any) error
 	TryPublish(topic string, args ...any) error
+	PublishWait(ctx context.Context, topic string, args ...any) error
+	PublishMany(topics []string, args ...any) error
+	PublishAfter(topic string, delay time.Duration, args ...any) (cancel func(), err error)
+	PublishIfSubscribed(topic string, build func() []any) (bool, error)
+	PublishEvent(topic string, payload any) (eventID string, err error)
+	PublishWithID(topic, correlationID string, args ...any) error
+	PublishAck(topic string, args ...any) (<-chan struct{}, error)
 }
 
-// PubSub is the interface that groups the Subscriber and Publisher interfaces.
-// It also adds the CloseTopic and Shutdown methods.
+// PubSub is the interface that groups the Subscriber, Publisher and Requester interfaces.
+// It also adds the Topics, HasTopic, IsClosed, HandlerStats, DroppedCount, TotalDropped, History, Snapshot, Range, Clone, WaitForSubscribers, Pause, Resume, CloseTopic, CloseSubtree, DeclareTopic, ExportTopics, ImportTopics, Alias, RemoveAlias, Shutdown, ShutdownExcept, Closed, Reset, Drain, Namespace, Do and Run methods.
+// Topics returns the names of all open topics.
+// HasTopic reports whether a topic exists and is open.
+// IsClosed reports whether a topic exists and has been closed.
+// HandlerStats returns how long each handler of the topic took to run.
+// DroppedCount returns the number of messages published to the topic that were discarded.
+// TotalDropped returns the number of discarded messages of all topics.
+// History returns the last messages published to the topic.
+// Snapshot returns the number of handlers of each topic.
+// Range calls a function with the number of handlers of each topic until it returns false.
+// Clone returns an independent copy of the PubSub with the same topics and handlers.
+// WaitForSubscribers blocks until the topic has a given number of handlers.
+// Pause stops delivery to the topic without removing its handlers.
+// Resume restarts delivery to a paused topic.
 // CloseTopic removes all handlers from the topic and deletes the topic.
+// CloseSubtree closes a topic and all topics below it in the dotted name hierarchy.
+// DeclareTopic declares a topic, which WithExplicitTopics requires before it is used.
+// ExportTopics returns the names of the open and declared topics.
+// ImportTopics declares the topics returned by ExportTopics.
+// Alias makes publishes to one topic also reach the handlers of another.
+// RemoveAlias removes an alias.
 // Shutdown removes all handlers from all topics and deletes all topics.
+// ShutdownExcept closes all topics except the given ones.
+// Closed reports whether the PubSub has been shut down.
+// Reset shuts the PubSub down and clears its state so it can be used again.
+// Drain waits until all queued messages have been delivered, without closing anything.
+// Namespace returns a view of the PubSub in which all topic names are prefixed.
+// Do performs the operation described by a Message.
+// Run performs the operations of Messages received from a channel.
 type PubSub interface {
 	Subscriber
 	Publisher
+	Requester
+	Topics() []string
+	HasTopic(topic string) bool
+	IsClosed(topic string) bool
+	HandlerStats(topic string) []HandlerTiming
+	DroppedCount(topic string) uint64
+	TotalDropped() uint64
+	History(topic string, n int) [][]any
+	Snapshot() map[string]int
+	Range(fn func(topic string, subscriberCount int) bool)
+	Clone() PubSub
+	WaitForSubscribers(ctx context.Context, topic string, n int) error
+	Pause(topic string) error
+	Resume(topic string) error
 	CloseTopic(topic string) error
+	CloseSubtree(prefix string) (int, error)
+	DeclareTopic(topic string) error
+	ExportTopics() []string
+	ImportTopics(topics []string) error
+	Alias(from, to string) error
+	RemoveAlias(from string) error
 	Shutdown() error
+	ShutdownExcept(topics ...string) error
+	Closed() bool
+	Reset() error
+	Drain(ctx context.Context) error
+	Namespace(prefix string) PubSub
+	Do(msg Message) error
+	Run(ctx context.Context, commands <-chan Message) error
 }
 
-// New returns a new PubSub instance.
-func New() PubSub {
-	return &pubsub{
-		topics: make(map[string]*topic),
+// New returns a new PubSub instance configured by the given options.
+func New(opts ...Option) PubSub {
+	p := &pubsub{
+		opts: defaultOptions(),
+	}
+	for _, opt := range opts {
+		opt(&p.opts)
+	}
+	p.normalizePartitioners()
+	p.normalizeRateLimits()
+	p.shards = make([]topicShard, p.opts.shards)
+	for i := range p.shards {
+		p.shards[i].topics = make(map[string]*topic)
+	}
+	p.start()
+	return p
+}
+
+// start starts the goroutines the options of p need: the dispatcher of WithGlobalOrder
+// and the sweeper of WithTopicTTL.
+func (p *pubsub) start() {
+	if p.opts.globalOrder {
+		p.dispatcher = newDispatcher(p, p.opts.globalOrderWait)
+	}
+	if p.opts.topicTTL > 0 {
+		p.sweepStop = make(chan struct{})
+		go p.sweep(p.sweepStop)
 	}
 }
 
 type pubsub struct {
+	// shards holds the topics, spread by the hash of their name so that
+	// operations on unrelated topics don't contend for the same lock.
+	shards []topicShard
+	opts   options
+	// dispatcher is nil unless publishes are delivered in global order.
+	dispatcher *dispatcher
+	// sweepStop is nil unless idle topics expire. It is closed to stop the sweeper.
+	sweepStop     chan struct{}
+	sweepStopOnce sync.Once
+	// shutdown is set by Shutdown, after which subscribing and publishing fail.
+	shutdown atomic.Bool
+
+	// lastID is the last SubscriptionID handed out.
+	lastID atomic.Uint64
+	// drops is the number of discarded messages of all topics, including removed ones.
+	drops atomic.Uint64
+	// openTopics is the number of open topics, which is reported to the metrics sink.
+	openTopics atomic.Int64
+	// inFlight is the number of deliveries in progress on all topics, see topic.loops.
+	inFlight atomic.Int64
+
+	// timers holds the pending publishes scheduled by PublishAfter.
+	timersMu  sync.Mutex
+	timers    map[uint64]Timer
+	nextTimer uint64
+
+	// aliases maps each alias set by Alias to its target topic.
+	aliasesMu sync.RWMutex
+	aliases   map[string]string
+
+	// declared holds the topics declared with DeclareTopic.
+	declaredMu sync.RWMutex
+	declared   map[string]bool
+
+	// taps are called with every publish, to forward them to the PubSubs bridged to.
+	// The slice is replaced rather than modified, so it can be iterated without tapsMu.
+	tapsMu sync.RWMutex
+	taps   []*tap
+	// defaults are the handlers added with SubscribeDefault. Like taps, the slice is
+	// replaced rather than modified.
+	defaultsMu sync.RWMutex
+	defaults   []*defaultHandler
+	// bridges counts the bridges to each PubSub, to refuse bridging in a circle.
+	// It is guarded by bridgesMu.
+	bridges map[*pubsub]int
+}
+
+// topicShard is a stripe of the topics map with its own lock.
+type topicShard struct {
+	mu     sync.RWMutex
 	topics map[string]*topic
 }
 
+// shard returns the shard the named topic belongs to.
+func (p *pubsub) shard(name string) *topicShard {
+	if len(p.shards) == 1 {
+		return &p.shards[0]
+	}
+	// Inlined 32-bit FNV-1a, which avoids allocating a hash.Hash32.
+	h := uint32(2166136261)
+	for i := 0; i < len(name); i++ {
+		h ^= uint32(name[i])
+		h *= 16777619
+	}
+	return &p.shards[h%uint32(len(p.shards))]
+}
+
 // Shutdown removes all handlers from all topics and deletes all topics.
 func (p *pubsub) Subscribe(topic string, handler func(...any)) error {
-	return p.subscribe(topic, handler, false, false)
+	_, err := p.subscribe(topic, &subscription{handler: handler})
+	return err
 }
 
 // SubscribeOnce adds a handler to the topic and removes it after the first call.
-func (p *pubsub) SubscribeOnce(topic string, handler func(...any)) error {
-	return p.subscribe(topic, handler, true, false)
+// The returned cancel function removes the handler before it was called; once it was,
+// cancel is a no-op.
+func (p *pubsub) SubscribeOnce(topic string, handler func(...any)) (cancel func(), err error) {
+	return p.subscribe(topic, &subscription{handler: handler, once: true})
 }
 
 // SubscribeOnceEach adds a handler to the topic and removes it after the first call for each handler.
-func (p *pubsub) SubscribeOnceEach(topic string, handler func(...any)) error {
-	return p.subscribe(topic, handler, true, true)
+// The returned cancel function removes the handler before it was called; once it was,
+// cancel is a no-op.
+func (p *pubsub) SubscribeOnceEach(topic string, handler func(...any)) (cancel func(), err error) {
+	return p.subscribe(topic, &subscription{handler: handler, once: true, onceEach: true})
 }
 
-// CloseTopic removes all handlers from the topic and deletes the topic.
-func (p *pubsub) subscribe(topic string, handler func(...any), once, onceEach bool) error {
-	t, ok := p.topics[topic]
+// SubscribeOnceEachKeyed adds a handler to the topic that is removed after its first call,
+// like SubscribeOnceEach, and identifies it by key. Subscribing again with the same key
+// before the handler was called replaces it in place instead of adding another one, so a
+// key fires at most once per publish however often it is subscribed. Once it fired, the
+// key can be subscribed again.
+func (p *pubsub) SubscribeOnceEachKeyed(topic, key string, handler func(...any)) error {
+	_, err := p.subscribe(topic, &subscription{handler: handler, once: true, onceEach: true, key: key})
+	return err
+}
+
+// SubscribeWithClose adds onMsg to the topic and calls onClose once onMsg is removed, whether
+// by the returned cancel function, Unsubscribe, UnsubscribeAll, CloseTopic or Shutdown, so
+// the handler can release what it holds. onClose is called at most once, and not at all
+// if subscribing fails.
+func (p *pubsub) SubscribeWithClose(topic string, onMsg func(...any), onClose func()) (cancel func(), err error) {
+	sub := &subscription{handler: onMsg}
+	if onClose != nil {
+		var once sync.Once
+		sub.stop = func() { once.Do(onClose) }
+	}
+	return p.subscribe(topic, sub)
+}
+
+// SubscribeStateful2 calls snapshot and delivers its result to the handler, then adds the handler to the topic.
+// It waits until no message is being delivered to the topic and holds back new deliveries until
+// the handler is added, so a message published while the snapshot is taken is either reflected
+// in the snapshot or delivered afterwards, never missed or duplicated. It must therefore not be
+// called from a handler of the same topic.
+func (p *pubsub) SubscribeStateful2(topic string, snapshot func() []any, handler func(...any)) error {
+	if handler == nil {
+		return ErrNilHandler
+	}
+	topic, err := p.topicName(topic)
+	if err != nil {
+		return err
+	}
+	if err := p.known(topic); err != nil {
+		return err
+	}
+	return p.getOrCreateTopic(topic).subscribeStateful(snapshot, handler)
+}
+
+// SubscribeWithID adds a handler to the topic and returns an ID that identifies the
+// subscription, which can be passed to UnsubscribeByID to remove it.
+func (p *pubsub) SubscribeWithID(topic string, handler func(...any)) (SubscriptionID, error) {
+	sub := &subscription{handler: handler}
+	if _, err := p.subscribe(topic, sub); err != nil {
+		return 0, err
+	}
+	return sub.id, nil
+}
+
+// SubscribeFiltered adds a handler to the topic that is only called for messages for which
+// filter returns true. The returned cancel function removes the handler from the topic.
+func (p *pubsub) SubscribeFiltered(topic string, filter func(args ...any) bool, handler func(...any)) (cancel func(), err error) {
+	return p.subscribe(topic, &subscription{handler: handler, filter: filter})
+}
+
+// subscribe adds sub to the topic and returns a function that removes it again.
+func (p *pubsub) subscribe(topic string, sub *subscription) (cancel func(), err error) {
+	if sub.handler == nil {
+		return nil, ErrNilHandler
+	}
+	topic, err = p.topicName(topic)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.known(topic); err != nil {
+		return nil, err
+	}
+	for {
+		t := p.getOrCreateTopic(topic)
+		err := t.subscribe(sub)
+		if err == ErrTopicClosed && p.removed(t) {
+			// The topic was removed, for example because it expired, after we looked
+			// it up. Subscribe to a new one rather than to a topic that is gone; one
+			// closed with CloseTopic stays registered and keeps refusing handlers.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return func() { t.remove(sub) }, nil
+	}
+}
+
+// removed reports whether t is no longer the topic registered under its name.
+func (p *pubsub) removed(t *topic) bool {
+	current, ok := p.getTopic(t.name)
+	return !ok || current != t
+}
+
+// getOrCreateTopic returns the named topic, creating it if it does not exist yet.
+func (p *pubsub) getOrCreateTopic(name string) *topic {
+	if t, ok := p.getTopic(name); ok {
+		return t
+	}
+	sh := p.shard(name)
+	sh.mu.Lock()
+	t, ok := sh.topics[name]
 	if !ok {
-		t = newTopic(topic)
-		p.topics[topic] = t
+		t = newTopic(p, name)
+		sh.topics[name] = t
 	}
-	return t.subscribe(handler, once, onceEach)
+	sh.mu.Unlock()
+	if !ok {
+		p.topicsChanged(1)
+		p.systemEvent(TopicCreated, name, 0)
+	}
+	return t
 }
 
-// CloseTopic removes all handlers from the topic and deletes the topic.
+// getTopic returns the named topic if it exists.
+func (p *pubsub) getTopic(name string) (*topic, bool) {
+	sh := p.shard(name)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	t, ok := sh.topics[name]
+	return t, ok
+}
+
+// Topics returns the names of all open topics in sorted order. The topics the PubSub
+// publishes to itself, such as the dead-letter topic and request topics, aren't included.
+func (p *pubsub) Topics() []string {
+	var names []string
+	for _, t := range p.allTopics() {
+		if !t.isClosed() && !p.internal(t.name) {
+			names = append(names, t.name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasTopic reports whether the topic exists and is open.
+func (p *pubsub) HasTopic(topic string) bool {
+	t, ok := p.lookupTopic(topic)
+	return ok && !t.isClosed()
+}
+
+// IsClosed reports whether the topic exists and has been closed.
+func (p *pubsub) IsClosed(topic string) bool {
+	t, ok := p.lookupTopic(topic)
+	return ok && t.isClosed()
+}
+
+// allTopics returns a snapshot of all topics. The shard locks are only held while it is
+// taken, so callers such as Shutdown can close and remove the topics, and handlers called
+// meanwhile can create new ones, without mutating a map that is being iterated over.
+func (p *pubsub) allTopics() []*topic {
+	var topics []*topic
+	for i := range p.shards {
+		sh := &p.shards[i]
+		sh.mu.RLock()
+		for _, t := range sh.topics {
+			topics = append(topics, t)
+		}
+		sh.mu.RUnlock()
+	}
+	return topics
+}
+
+// Unsubscribe removes all handlers from the topic and closes it, like CloseTopic, except that
+// the sequence of its Events doesn't restart.
 func (p *pubsub) Unsubscribe(topic string) error {
-	t, ok := p.topics[topic]
+	topic, err := p.topicName(topic)
+	if err != nil {
+		return err
+	}
+	t, ok := p.getTopic(topic)
 	if !ok {
 		return nil
 	}
 	return t.unsubscribe()
 }
 
-// UnsubscribeAll removes all handlers from all topics.
+// UnsubscribeByID removes the handler with the given ID, as returned by SubscribeWithID, from the topic.
+// It is a no-op if there is no such handler.
+func (p *pubsub) UnsubscribeByID(topic string, id SubscriptionID) error {
+	topic, err := p.topicName(topic)
+	if err != nil {
+		return err
+	}
+	t, ok := p.getTopic(topic)
+	if !ok {
+		return nil
+	}
+	t.removeIf(func(sub *subscription) bool { return sub.id == id })
+	return nil
+}
+
+// UnsubscribeHandler removes the first handler of the topic that is the same func as handler.
+// Funcs are compared by their code pointer, so all closures created by the same func literal
+// count as the same handler, whatever variables they captured; use SubscribeWithID to tell
+// those apart. Handlers that were wrapped when subscribing, such as by SubscribeEvents, are
+// never matched. It is a no-op if there is no such handler.
+func (p *pubsub) UnsubscribeHandler(topic string, handler func(...any)) error {
+	topic, err := p.topicName(topic)
+	if err != nil {
+		return err
+	}
+	t, ok := p.getTopic(topic)
+	if !ok {
+		return nil
+	}
+	ptr := reflect.ValueOf(handler).Pointer()
+	t.removeIf(func(sub *subscription) bool {
+		return reflect.ValueOf(sub.handler).Pointer() == ptr
+	})
+	return nil
+}
+
+// UnsubscribeAll removes all handlers from all topics and closes them, like Unsubscribe.
+// It tries every topic and returns the errors of all that failed, joined.
 func (p *pubsub) UnsubscribeAll() error {
-	for _, t := range p.topics {
+	var errs []error
+	for _, t := range p.allTopics() {
 		if err := t.unsubscribe(); err != nil {
-			return err
+			errs = append(errs, err)
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 // Publish calls all handlers for the topic.
 func (p *pubsub) Publish(topic string, args ...any) error {
-	return p.publish(topic, args, false)
+	if p.unheard(topic) {
+		return nil
+	}
+	return p.publish(context.Background(), topic, append([]any(nil), args...), false, nil)
 }
 
-// TryPublish calls all handlers for the topic and returns the first error.
+// TryPublish calls all handlers for the topic and returns the errors of the handlers
+// subscribed with SubscribeErr that failed, each wrapped in a HandlerError and joined.
 func (p *pubsub) TryPublish(topic string, args ...any) error {
-	return p.publish(topic, args, true)
+	if p.unheard(topic) {
+		return nil
+	}
+	return p.publish(context.Background(), topic, append([]any(nil), args...), true, nil)
+}
+
+// PublishWait calls all handlers for the topic like Publish, but while the queue of an
+// asynchronous topic is full it only waits for room until ctx is done, and then returns
+// the context's error without queuing the message. This lets slow producers wait for
+// consumers without blocking forever, unlike Publish, or failing fast, like TryPublish.
+func (p *pubsub) PublishWait(ctx context.Context, topic string, args ...any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.publish(ctx, topic, append([]any(nil), args...), false, nil)
+}
+
+// unheard reports whether a message published to the topic can be dropped right away
+// because no handler would receive it and nothing else records it.
+// Publish and TryPublish check it first and only copy args when it returns false, so
+// args doesn't escape and publishing to a topic without handlers doesn't allocate.
+func (p *pubsub) unheard(topic string) bool {
+	if p.dispatcher != nil || p.opts.logger != nil || p.opts.metrics != nil || p.opts.historySize > 0 || p.opts.explicitTopics || p.shutdown.Load() {
+		return false
+	}
+	topic, err := p.topicName(topic)
+	if err != nil || p.hasAlias(topic) || p.tapped() || p.hasDefaults() {
+		return false
+	}
+	t, ok := p.getTopic(topic)
+	if !ok {
+		return true
+	}
+	t.mu.Lock()
+	unheard := t.subs.len() == 0 && !t.paused
+	t.mu.Unlock()
+	if unheard {
+		// Publishing still keeps the topic from expiring.
+		t.touch()
+	}
+	return unheard
+}
+
+// publish delivers a message to the topic and the topics aliased to it. Waiting for room
+// in the queue of an asynchronous topic stops when ctx is done. If ack is not nil, it is
+// added to for each queued delivery, which marks it done once delivered.
+func (p *pubsub) publish(ctx context.Context, topic string, args []any, try bool, ack *sync.WaitGroup) error {
+	topic, err := p.topicName(topic)
+	if err != nil {
+		return err
+	}
+	if err := p.known(topic); err != nil {
+		return err
+	}
+	_, err = p.send(ctx, topic, args, try, false, ack)
+	return err
+}
+
+// errNotDelivered is returned by the delivery of a message published with
+// PublishIfSubscribed to a topic that doesn't take it.
+var errNotDelivered = errors.New("pubsub: message not delivered")
+
+// send delivers a message to a checked topic name and the topics aliased to it. If
+// ifSubscribed is set, the message is only delivered to the topics that have handlers
+// when it reaches them, and send reports whether any of them took it.
+func (p *pubsub) send(ctx context.Context, topic string, args []any, try, ifSubscribed bool, ack *sync.WaitGroup) (bool, error) {
+	p.count(MetricPublishes, topic)
+	p.callTaps(topic, args)
+	targets := p.aliased(topic)
+	err := p.route(ctx, topic, args, try, ifSubscribed, ack)
+	delivered := !errors.Is(err, errNotDelivered)
+	if !delivered {
+		err = nil
+	}
+	if len(targets) == 0 {
+		return delivered, err
+	}
+	errs := []error{err}
+	for _, target := range targets {
+		err := p.route(ctx, target, args, try, ifSubscribed, ack)
+		if errors.Is(err, errNotDelivered) {
+			continue
+		}
+		delivered = true
+		errs = append(errs, err)
+	}
+	return delivered, errors.Join(errs...)
+}
+
+// route delivers a message to the topic, through the dispatcher if there is one.
+func (p *pubsub) route(ctx context.Context, topic string, args []any, try, ifSubscribed bool, ack *sync.WaitGroup) error {
+	if p.dispatcher != nil {
+		op := Publish
+		if try {
+			op = TryPublish
+		}
+		return p.dispatcher.enqueue(Message{Topic: topic, Operation: op, Args: args}, ifSubscribed, ack)
+	}
+	return p.deliver(ctx, topic, args, try, ifSubscribed, ack)
+}
+
+// deliver calls the handlers of the topic. If ifSubscribed is set and the topic has no
+// handlers, it drops the message and returns errNotDelivered.
+func (p *pubsub) deliver(ctx context.Context, topic string, args []any, try, ifSubscribed bool, ack *sync.WaitGroup) error {
+	if p.opts.historySize > 0 && !ifSubscribed {
+		return p.getOrCreateTopic(topic).publish(ctx, args, try, false, ack)
+	}
+	t, ok := p.getTopic(topic)
+	if !ok {
+		if ifSubscribed {
+			return errNotDelivered
+		}
+		p.logEvent("publish", topic, 0)
+		p.callDefaults(topic, args)
+		return nil
+	}
+	return t.publish(ctx, args, try, ifSubscribed, ack)
+}
+
+// WaitForSubscribers blocks until at least n handlers are subscribed to the topic.
+// It returns the context's error if ctx is done first, and ErrTopicClosed if the topic is closed.
+func (p *pubsub) WaitForSubscribers(ctx context.Context, topic string, n int) error {
+	topic, err := p.topicName(topic)
+	if err != nil {
+		return err
+	}
+	if err := p.known(topic); err != nil {
+		return err
+	}
+	return p.getOrCreateTopic(topic).waitForSubscribers(ctx, n)
+}
+
+// Pause stops delivery to the topic without removing its handlers.
+// Messages published while the topic is paused are dropped, or buffered until Resume
+// if the PubSub was created with WithPauseBuffer.
+func (p *pubsub) Pause(topic string) error {
+	topic, err := p.topicName(topic)
+	if err != nil {
+		return err
+	}
+	if err := p.known(topic); err != nil {
+		return err
+	}
+	return p.getOrCreateTopic(topic).pause()
 }
 
-func (p *pubsub) publish(topic string, args []any, try bool) error {
-	t, ok := p.topics[topic]
+// Resume restarts delivery to a paused topic, first delivering any buffered messages in order.
+// Messages published meanwhile are delivered after them. Pausing the topic again stops the
+// delivery of the buffered messages; the rest stay buffered.
+func (p *pubsub) Resume(topic string) error {
+	topic, err := p.topicName(topic)
+	if err != nil {
+		return err
+	}
+	t, ok := p.getTopic(topic)
 	if !ok {
 		return nil
 	}
-	return t.publish(args, try)
+	return t.resume()
 }
 
 // CloseTopic removes all handlers from the topic and deletes the topic.
 func (p *pubsub) CloseTopic(topic string) error {
-	t, ok := p.topics[topic]
+	topic, err := p.topicName(topic)
+	if err != nil {
+		return err
+	}
+	t, ok := p.getTopic(topic)
 	if !ok {
 		return nil
 	}
 	return t.close()
 }
 
+// ErrNilHandler is returned when subscribing a nil handler, or a nil function the handler
+// depends on, such as the key function of SubscribeKeyed.
+var ErrNilHandler = errors.New("pubsub: nil handler")
+
+// ErrTopicClosed is returned when subscribing to a closed topic.
+var ErrTopicClosed = errors.New("pubsub: topic closed")
+
+// PublishIfSubscribed calls build and publishes its result to the topic only if the topic,
+// or a topic aliased to it, has at least one handler, and reports whether it did. The
+// message is published like Publish, except that each topic only takes it if it has
+// handlers when the message reaches it. Checking for handlers and delivering happen
+// atomically, so the message reaches the handlers that were checked. On an asynchronous
+// PubSub the check happens when the message is queued. With WithGlobalOrder, unless the
+// publisher waits for delivery, it reports whether there were handlers when it was called.
+func (p *pubsub) PublishIfSubscribed(topic string, build func() []any) (bool, error) {
+	topic, err := p.topicName(topic)
+	if err != nil {
+		return false, err
+	}
+	if err := p.known(topic); err != nil {
+		return false, err
+	}
+	if !p.subscribed(topic) {
+		return false, nil
+	}
+	return p.send(context.Background(), topic, build(), false, true, nil)
+}
+
+// subscribed reports whether the topic or a topic aliased to it has handlers a message
+// published now would reach.
+func (p *pubsub) subscribed(topic string) bool {
+	for _, name := range append([]string{topic}, p.aliased(topic)...) {
+		t, ok := p.getTopic(name)
+		if !ok {
+			continue
+		}
+		t.mu.Lock()
+		ok = !t.closed && !t.paused && t.subs.len() > 0
+		t.mu.Unlock()
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrWouldBlock is returned by TryPublish on an asynchronous PubSub when the topic's queue is full.
+var ErrWouldBlock = errors.New("pubsub: publish would block")
+
+// ErrTooManySubscribers is returned when subscribing to a topic that already has
+// the maximum number of handlers set by WithMaxSubscribers.
+var ErrTooManySubscribers = errors.New("pubsub: too many subscribers")
+
+// ErrUndelivered is returned when closing an asynchronous topic drops messages that were still queued.
+var ErrUndelivered = errors.New("pubsub: messages left undelivered")
+
+// ErrHandlerPanic is published to the dead-letter topic with a message whose handler panicked.
+var ErrHandlerPanic = errors.New("pubsub: handler panicked")
+
+// deadLetter publishes args followed by err to the dead-letter topic, if there is one.
+func (p *pubsub) deadLetter(args []any, err error) {
+	if p.opts.deadLetterTopic == "" {
+		return
+	}
+	msg := make([]any, len(args), len(args)+1)
+	copy(msg, args)
+	_ = p.Publish(p.opts.deadLetterTopic, append(msg, err)...)
+}
+
+// CloseSubtree closes the topic named prefix and every topic whose name starts with
+// prefix followed by a dot, so "tenant1" matches "tenant1.orders" but not "tenant10".
+// It returns the number of topics that were closed.
+func (p *pubsub) CloseSubtree(prefix string) (int, error) {
+	closed := 0
+	for _, t := range p.allTopics() {
+		if t.name != prefix && !strings.HasPrefix(t.name, prefix+".") {
+			continue
+		}
+		if t.closeOpen(true) {
+			closed++
+		}
+	}
+	return closed, nil
+}
+
+// removeTopic closes the topic and deletes it from the topics map.
+func (p *pubsub) removeTopic(name string) {
+	sh := p.shard(name)
+	sh.mu.Lock()
+	t, ok := sh.topics[name]
+	delete(sh.topics, name)
+	sh.mu.Unlock()
+	if ok {
+		_ = t.close()
+	}
+}
+
+// ErrShutdown is returned when subscribing or publishing to a PubSub that has been shut down.
+var ErrShutdown = errors.New("pubsub: shut down")
+
 // Shutdown removes all handlers from all topics and deletes all topics.
+// It closes every topic even if closing some fails, and returns their errors joined.
+// Subscribing and publishing afterwards return ErrShutdown.
 func (p *pubsub) Shutdown() error {
-	for _, t := range p.topics {
+	p.shutdown.Store(true)
+	p.stopTimers()
+	if p.sweepStop != nil {
+		p.sweepStopOnce.Do(func() { close(p.sweepStop) })
+	}
+	if p.dispatcher != nil {
+		p.dispatcher.stop()
+	}
+	var errs []error
+	for _, t := range p.allTopics() {
 		if err := t.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Closed reports whether Shutdown has been called. It is false again after Reset.
+func (p *pubsub) Closed() bool {
+	return p.shutdown.Load()
+}
+
+// ShutdownExcept closes all topics except the given ones, which keep their handlers and
+// go on delivering. Unlike Shutdown it leaves scheduled publishes and the dispatcher of an
+// asynchronous PubSub running.
+func (p *pubsub) ShutdownExcept(topics ...string) error {
+	keep := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		name, err := p.topicName(topic)
+		if err != nil {
 			return err
 		}
+		keep[name] = true
 	}
-	return nil
+	return closeExcept(p.allTopics(), keep)
+}
+
+// closeExcept closes the topics whose names are not in keep.
+func closeExcept(topics []*topic, keep map[string]bool) error {
+	var errs []error
+	for _, t := range topics {
+		if keep[t.name] {
+			continue
+		}
+		if err := t.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 type topic struct {
-	name     string
-	handlers []func(...any)
+	p    *pubsub
+	name string
+	// limiter is nil unless the topic is rate limited. limitErr is set instead if the
+	// rate limit is invalid.
+	limiter  *tokenBucket
+	limitErr error
+	// partitioner is nil unless the topic is partitioned. See SubscribePartition.
+	partitioner func(args ...any) int
+	// timings is nil unless handler timing is enabled.
+	timings *handlerTimings
+	// history is nil unless the topic keeps its published messages.
+	history *history
+
+	// mu guards the fields below.
+	mu sync.Mutex
+	// subs holds the topic's subscriptions in the order handlers are called in.
+	subs   handlerSet
+	closed bool
+	paused bool
+	// buffered holds the messages published while paused.
+	buffered [][]any
+	// resuming is set by Resume and cleared by Pause. flushing is set while Resume delivers
+	// the buffered messages; the topic stays paused until they are all delivered, so new
+	// messages are buffered behind them instead of overtaking them.
+	resuming bool
+	flushing bool
+	// changed, if not nil, is closed when a subscription is added or the topic is closed.
+	changed chan struct{}
+
+	// lastUsed is when the topic was last subscribed or published to, in Unix nanoseconds.
+	// It is only maintained if idle topics expire.
+	lastUsed atomic.Int64
+	// seq is the sequence number of the last Event published to the topic.
+	seq atomic.Uint64
+
+	// delivering is the number of deliveries whose handlers are being invoked.
+	// A stateful subscriber waits for it to drop to zero and then sets snapshotting,
+	// which holds back new deliveries until it is subscribed. Unlike a read lock held
+	// during delivery, this lets handlers publish to their own topic while a stateful
+	// subscriber is waiting. While one waits, counted by stateful, new deliveries other
+	// than those nested in a delivery hold back too, so that publishers can't keep it
+	// waiting forever. idle is signalled when any of them changes.
+	delivering   int
+	snapshotting bool
+	stateful     int
+	idle         sync.Cond
+
+	// queue and done are nil unless the PubSub delivers asynchronously.
+	// queue holds the published messages until the topic's worker delivers them,
+	// and done is closed when the topic is closed to stop the worker.
+	queue chan queued
+	done  chan struct{}
+	// pending is the number of queued messages that haven't been delivered yet.
+	pending atomic.Int64
+	// drained, if not nil, is closed when no message is pending or being delivered.
+	// It is guarded by mu.
+	drained chan struct{}
+	// lastBackpressure is when the backpressure callback was last called, in Unix nanoseconds.
+	lastBackpressure atomic.Int64
+	// drops is the number of messages published to the topic that were discarded.
+	drops atomic.Uint64
+}
+
+func newTopic(p *pubsub, name string) *topic {
+	t := &topic{
+		p:    p,
+		name: name,
+		subs: &sliceHandlerSet{},
+	}
+	t.idle.L = &t.mu
+	if limit, ok := p.opts.rateLimits[name]; ok {
+		t.limiter, t.limitErr = newTokenBucket(limit, p.opts.clock)
+	}
+	t.partitioner = p.opts.partitioners[name]
+	if p.opts.handlerTiming {
+		t.timings = &handlerTimings{}
+	}
+	if p.opts.historySize > 0 {
+		t.history = newHistory(p.opts.historySize)
+	}
+	t.touch()
+	if p.opts.async {
+		t.queue = make(chan queued, p.opts.asyncBuffer)
+		t.done = make(chan struct{})
+		go t.work()
+	}
+	return t
+}
+
+// SubscriptionID identifies a handler registered on a topic.
+type SubscriptionID uint64
+
+// subscription is a handler registered on a topic.
+type subscription struct {
+	// id is assigned when the subscription is added to a topic.
+	id      SubscriptionID
+	handler func(...any)
+	// handleErr, if set, is called instead of handler and its error reported to TryPublish.
+	handleErr func(...any) error
+	// filter, if set, must return true for the handler to be called.
+	filter func(args ...any) bool
+	// partitioned makes the handler only receive the messages of partition, as assigned
+	// by the topic's partitioner once per message.
+	partitioned bool
+	partition   int
+	// once removes the subscription after its first delivery.
 	once     bool
 	onceEach bool
-	closed   bool
+	// key, if not empty, identifies the subscription within its topic. Subscribing with
+	// the key of an existing subscription replaces it. See SubscribeOnceEachKeyed.
+	key string
+	// stop, if set, is called when the subscription is removed from the topic.
+	stop func()
 }
 
-func newTopic(name string) *topic {
-	return &topic{
-		name: name,
+// stopAll calls the stop functions of subs, which have been removed from a topic.
+func stopAll(subs []*subscription) {
+	for _, sub := range subs {
+		if sub.stop != nil {
+			sub.stop()
+		}
 	}
 }
 
-func (t *topic) subscribe(handler func(...any), once, onceEach bool) error {
+func (t *topic) subscribe(sub *subscription) error {
+	t.mu.Lock()
+	var replaced *subscription
+	if sub.key != "" {
+		replaced = t.replace(sub)
+	}
+	if replaced == nil {
+		if err := t.canAdd(); err != nil {
+			t.mu.Unlock()
+			return err
+		}
+		t.add(sub)
+	}
+	n := t.subs.len()
+	t.mu.Unlock()
+	if replaced != nil {
+		stopAll([]*subscription{replaced})
+	}
+	t.touch()
+	t.p.logEvent("subscribe", t.name, n)
+	t.p.systemEvent(SubscriberAdded, t.name, n)
+	return nil
+}
+
+func (t *topic) isClosed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closed
+}
+
+// canAdd returns an error if no subscription can be added to the topic.
+// It must be called with mu held.
+func (t *topic) canAdd() error {
 	if t.closed {
-		return nil
+		return ErrTopicClosed
+	}
+	if limit := t.p.opts.maxSubscribers; limit > 0 && t.subs.len() >= limit {
+		return ErrTooManySubscribers
 	}
-	t.handlers = append(t.handlers, handler)
-	t.once = once
-	t.onceEach = onceEach
 	return nil
 }
 
-func (t *topic) unsubscribe() error {
+// add appends sub to the topic's subscriptions. It must be called with mu held.
+func (t *topic) add(sub *subscription) {
+	if sub.id == 0 {
+		sub.id = SubscriptionID(t.p.lastID.Add(1))
+	}
+	t.subs.add(sub)
+	t.notify()
+}
+
+// replace puts sub in the place of the subscription with the same key and returns that
+// one, or returns nil if there is none. It must be called with mu held.
+func (t *topic) replace(sub *subscription) *subscription {
+	replaced := t.subs.replace(func(s *subscription) bool { return s.key == sub.key }, sub)
+	if replaced != nil {
+		if sub.id == 0 {
+			sub.id = SubscriptionID(t.p.lastID.Add(1))
+		}
+		t.notify()
+	}
+	return replaced
+}
+
+// notify wakes up everyone waiting for the topic to change. It must be called with mu held.
+func (t *topic) notify() {
+	if t.changed != nil {
+		close(t.changed)
+		t.changed = nil
+	}
+}
+
+// remove removes sub from the topic. It is a no-op if sub was already removed.
+func (t *topic) remove(sub *subscription) {
+	t.removeIf(func(s *subscription) bool { return s == sub })
+}
+
+// removeIf removes the first subscription for which match returns true.
+func (t *topic) removeIf(match func(*subscription) bool) {
+	t.mu.Lock()
+	s := t.subs.remove(match)
+	n := t.subs.len()
+	t.mu.Unlock()
+	if s == nil {
+		return
+	}
+	stopAll([]*subscription{s})
+	t.p.logEvent("unsubscribe", t.name, n)
+	t.p.systemEvent(SubscriberRemoved, t.name, n)
+}
+
+func (t *topic) subscribeStateful(snapshot func() []any, handler func(...any)) error {
+	t.mu.Lock()
+	t.stateful++
+	for t.snapshotting || t.delivering > 0 {
+		t.idle.Wait()
+	}
+	t.stateful--
 	if t.closed {
+		t.idle.Broadcast()
+		t.mu.Unlock()
+		return ErrTopicClosed
+	}
+	t.snapshotting = true
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		t.snapshotting = false
+		t.idle.Broadcast()
+		t.mu.Unlock()
+	}()
+	handler(snapshot()...)
+	return t.subscribe(&subscription{handler: handler})
+}
+
+// waitForSnapshot waits until no stateful subscriber is taking its snapshot or, unless
+// the calling goroutine is delivering a message itself, waiting to take one. Nested
+// deliveries must not wait, as the delivery they are part of keeps the subscriber waiting.
+// It must be called with mu held.
+func (t *topic) waitForSnapshot() {
+	for t.snapshotting || (t.stateful > 0 && publishDepth() == 0) {
+		t.idle.Wait()
+	}
+}
+
+// endDelivery records that a delivery started with delivering++ is done.
+func (t *topic) endDelivery() {
+	t.p.inFlight.Add(-1)
+	t.mu.Lock()
+	t.delivering--
+	if t.delivering == 0 {
+		t.idle.Broadcast()
+		t.notifyDrained()
+	}
+	t.mu.Unlock()
+}
+
+func (t *topic) unsubscribe() error {
+	t.mu.Lock()
+	subs := t.subs.clear()
+	t.mu.Unlock()
+	stopAll(subs)
+	t.p.logEvent("unsubscribe", t.name, 0)
+	if len(subs) > 0 {
+		t.p.systemEvent(SubscriberRemoved, t.name, 0)
+	}
+	return t.closeAndDrop(false)
+}
+
+// publish delivers a message to the topic. If ifSubscribed is set, see publishIfSubscribed.
+func (t *topic) publish(ctx context.Context, args []any, try, ifSubscribed bool, ack *sync.WaitGroup) error {
+	t.touch()
+	if ifSubscribed {
+		return t.publishIfSubscribed(args)
+	}
+	if ok, err := t.limit(); !ok {
+		return err
+	}
+	if t.history != nil {
+		t.history.record(args)
+	}
+	if t.queue != nil {
+		return t.enqueue(ctx, queued{args: args, ack: ack}, try)
+	}
+	return t.deliver(args, try)
+}
+
+// limit applies the topic's rate limit, if any, and reports whether the message may be
+// delivered. It blocks until then or returns false, depending on the rate limit mode.
+// If the rate limit is invalid it returns false and the error.
+func (t *topic) limit() (bool, error) {
+	if t.limitErr != nil {
+		return false, t.limitErr
+	}
+	if t.limiter == nil {
+		return true, nil
+	}
+	if t.p.opts.rateLimitMode == RateLimitDrop {
+		if !t.limiter.allow() {
+			t.countDrops(1)
+			return false, nil
+		}
+		return true, nil
+	}
+	t.limiter.wait()
+	return true, nil
+}
+
+// publishIfSubscribed delivers a message to the topic only if it has handlers, and
+// returns errNotDelivered if it doesn't or the rate limit drops the message.
+func (t *topic) publishIfSubscribed(args []any) error {
+	if ok, err := t.limit(); !ok {
+		if err == nil {
+			err = errNotDelivered
+		}
+		return err
+	}
+	if t.queue != nil {
+		// Asynchronous handlers can only be checked when the message is queued.
+		t.mu.Lock()
+		ok := !t.closed && !t.paused && t.subs.len() > 0
+		t.mu.Unlock()
+		if !ok {
+			return errNotDelivered
+		}
+		if t.history != nil {
+			t.history.record(args)
+		}
+		return t.enqueue(context.Background(), queued{args: args}, false)
+	}
+	t.mu.Lock()
+	t.waitForSnapshot()
+	if t.closed || t.paused || t.subs.len() == 0 {
+		t.mu.Unlock()
+		return errNotDelivered
+	}
+	if t.loops() {
+		t.mu.Unlock()
+		return t.loopError()
+	}
+	subs, removed, n := t.takeSubs()
+	t.delivering++
+	t.p.inFlight.Add(1)
+	t.mu.Unlock()
+	defer t.endDelivery()
+	defer t.removedOnce(removed, n)
+	if t.history != nil {
+		t.history.record(args)
+	}
+	t.invoke(subs, args, nil)
+	return nil
+}
+
+// enqueue adds m to the queue of an asynchronous topic. If the queue is full it
+// blocks until ctx is done, or returns ErrWouldBlock if try is set. Messages published
+// to a closed topic are dropped.
+func (t *topic) enqueue(ctx context.Context, m queued, try bool) error {
+	t.checkBackpressure()
+	// Count the message as pending until it is delivered or it turns out it isn't queued.
+	t.pending.Add(1)
+	if m.ack != nil {
+		m.ack.Add(1)
+	}
+	select {
+	case t.queue <- m:
+		return nil
+	case <-t.done:
+		t.unqueue(1)
+		m.done()
 		return nil
+	default:
+	}
+	if t.overflow(m) {
+		return nil
+	}
+	if try {
+		t.unqueue(1)
+		m.done()
+		return ErrWouldBlock
+	}
+	select {
+	case t.queue <- m:
+	case <-t.done:
+		t.unqueue(1)
+		m.done()
+	case <-ctx.Done():
+		t.unqueue(1)
+		m.done()
+		return ctx.Err()
 	}
-	t.handlers = nil
-	t.closed = true
 	return nil
 }
 
-func (t *topic) publish(args []any, try bool) error {
+// work delivers the messages of an asynchronous topic until it is closed.
+func (t *topic) work() {
+	for {
+		select {
+		case m := <-t.queue:
+			_ = t.deliver(m.args, false)
+			t.unqueue(1)
+			m.done()
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// deliver calls the handlers of the topic with args. If try is set, it returns the errors
+// of the handlers that failed.
+func (t *topic) deliver(args []any, try bool) error {
+	t.mu.Lock()
+	t.waitForSnapshot()
 	if t.closed {
+		t.mu.Unlock()
 		return nil
 	}
-	for _, handler := range t.handlers {
-		handler(args...)
-		if t.once {
-			t.handlers = nil
+	if t.paused {
+		t.buffer(args)
+		t.mu.Unlock()
+		return nil
+	}
+	return t.deliverLocked(args, try)
+}
+
+// buffer keeps a message published while the topic is paused, or drops it if there is no
+// pause buffer or it is full. It must be called with mu held.
+func (t *topic) buffer(args []any) {
+	opts := &t.p.opts
+	if !opts.pauseBuffer || (opts.pauseBufferLimit > 0 && len(t.buffered) >= opts.pauseBufferLimit) {
+		t.countDrops(1)
+		if opts.pauseBuffer && opts.onDrop != nil {
+			opts.onDrop(t.name, args)
+		}
+		return
+	}
+	t.buffered = append(t.buffered, args)
+}
+
+// deliverLocked delivers args like deliver to an open topic that isn't paused. It must be
+// called with mu held, and releases it.
+func (t *topic) deliverLocked(args []any, try bool) error {
+	if t.loops() {
+		t.mu.Unlock()
+		return t.loopError()
+	}
+	subs, removed, n := t.takeSubs()
+	t.delivering++
+	t.p.inFlight.Add(1)
+	t.mu.Unlock()
+	defer t.endDelivery()
+	defer t.removedOnce(removed, n)
+	var errs []error
+	if try {
+		errs = make([]error, len(subs))
+	}
+	t.invoke(subs, args, errs)
+	if len(subs) == 0 {
+		t.p.callDefaults(t.name, args)
+	}
+	return errors.Join(errs...)
+}
+
+// takeSubs returns the subscriptions a message is delivered to and removes those that are
+// only delivered once, which it returns as removed along with the number of subscriptions
+// left. The remaining subscriptions are collected into a new slice, so the returned one is
+// never shifted while the handlers are called. It must be called with mu held.
+func (t *topic) takeSubs() (subs, removed []*subscription, n int) {
+	subs = t.subs.all()
+	removed = t.subs.removeAll(func(sub *subscription) bool { return sub.once })
+	return subs, removed, t.subs.len()
+}
+
+// removedOnce finishes the removal of the subscriptions takeSubs removed, once their
+// handlers have been called: it stops them and reports each removal. It must be called
+// without holding mu.
+func (t *topic) removedOnce(removed []*subscription, n int) {
+	if len(removed) == 0 {
+		return
+	}
+	stopAll(removed)
+	for range removed {
+		t.p.logEvent("unsubscribe", t.name, n)
+		t.p.systemEvent(SubscriberRemoved, t.name, n)
+	}
+}
+
+// invoke calls the handlers of subs with args. If errs is not nil, it has the same length
+// as subs and receives the error of each handler that failed at its index.
+// It is never inlined, so publishDepth can find it on the stack.
+//
+//go:noinline
+func (t *topic) invoke(subs []*subscription, args []any, errs []error) {
+	t.p.logEvent("publish", t.name, len(subs))
+	partition := 0
+	if t.partitioner != nil && len(subs) > 0 {
+		partition = t.partitioner(args...)
+	}
+	if t.p.opts.deliveryGoroutine && t.queue == nil {
+		t.invokeOnGoroutine(func() { t.invokeAll(subs, args, errs, partition) })
+		return
+	}
+	t.invokeAll(subs, args, errs, partition)
+}
+
+// invokeAll calls the handlers of subs with args, which belong to partition, on the calling
+// goroutine, or from the goroutines of WithWorkers. See invoke for errs.
+func (t *topic) invokeAll(subs []*subscription, args []any, errs []error, partition int) {
+	if workers := t.p.opts.workers; workers > 1 && len(subs) > 1 {
+		t.invokeParallel(subs, args, errs, partition, workers)
+		return
+	}
+	for i, sub := range subs {
+		t.invokeOne(i, sub, args, errs, partition)
+	}
+}
+
+// invokeOne calls the handler of sub, which is at index among the topic's handlers, if
+// its filter matches args and, for a partition handler, args belong to its partition.
+func (t *topic) invokeOne(index int, sub *subscription, args []any, errs []error, partition int) {
+	if sub.partitioned && sub.partition != partition {
+		return
+	}
+	if sub.filter != nil && !sub.filter(args...) {
+		return
+	}
+	if t.p.opts.argCopy {
+		args = append([]any(nil), args...)
+	}
+	t.call(index, sub, args, errs)
+}
+
+// call invokes the handler of sub, the handler at index. If a dead-letter topic is configured,
+// a panicking handler is recovered and the message is published to the dead-letter topic.
+// Panics of the dead-letter topic's own handlers are recovered and dropped.
+func (t *topic) call(index int, sub *subscription, args []any, errs []error) {
+	if t.timings != nil {
+		clock := t.p.opts.clock
+		start := clock.Now()
+		defer func() {
+			t.timings.record(index, clock.Now().Sub(start))
+		}()
+	}
+	if t.p.opts.slowHandler != nil {
+		defer t.watch(index).Stop()
+	}
+	if t.p.recovers() {
+		defer func() {
+			if r := recover(); r != nil {
+				t.panicked(index, sub, args, r)
+			}
+		}()
+	}
+	t.p.count(MetricDeliveries, t.name)
+	if sub.handleErr == nil {
+		sub.handler(args...)
+		return
+	}
+	if err := sub.handleErr(args...); err != nil {
+		t.p.count(MetricErrors, t.name)
+		herr := &HandlerError{Topic: t.name, Index: index, Err: err, Args: args}
+		if errs != nil {
+			errs[index] = herr
+		}
+		if t.name != t.p.opts.deadLetterTopic {
+			t.p.deadLetter(args, herr)
+		}
+		t.p.failed(herr)
+	}
+}
+
+func (t *topic) waitForSubscribers(ctx context.Context, n int) error {
+	for {
+		t.mu.Lock()
+		if t.closed {
+			t.mu.Unlock()
+			return ErrTopicClosed
+		}
+		if t.subs.len() >= n {
+			t.mu.Unlock()
+			return nil
+		}
+		if t.changed == nil {
+			t.changed = make(chan struct{})
 		}
-		if t.onceEach {
-			handler = nil
+		changed := t.changed
+		t.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
+}
+
+func (t *topic) pause() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.paused = true
+	t.resuming = false
 	return nil
 }
 
+// resume delivers the buffered messages one at a time, oldest first, and unpauses the
+// topic once none are left. If it is already flushing, for example because a handler
+// resumes the topic, the call returning first keeps going.
+func (t *topic) resume() error {
+	t.mu.Lock()
+	if !t.paused {
+		t.mu.Unlock()
+		return nil
+	}
+	t.resuming = true
+	if t.flushing {
+		t.mu.Unlock()
+		return nil
+	}
+	t.flushing = true
+	var errs []error
+	for {
+		t.waitForSnapshot()
+		if t.closed || !t.resuming {
+			// Closed, or paused again: what is left stays buffered.
+			t.flushing = false
+			t.mu.Unlock()
+			return errors.Join(errs...)
+		}
+		if len(t.buffered) == 0 {
+			t.paused = false
+			t.resuming = false
+			t.flushing = false
+			t.buffered = nil
+			t.mu.Unlock()
+			return errors.Join(errs...)
+		}
+		args := t.buffered[0]
+		t.buffered[0] = nil
+		t.buffered = t.buffered[1:]
+		if err := t.deliverLocked(args, false); err != nil {
+			errs = append(errs, err)
+		}
+		t.mu.Lock()
+	}
+}
+
 func (t *topic) close() error {
-	if t.closed {
+	return t.closeAndDrop(true)
+}
+
+// closeAndDrop closes the topic like close, but only resets its sequence if resetSeq is set.
+func (t *topic) closeAndDrop(resetSeq bool) error {
+	if !t.closeOpen(resetSeq) {
 		return nil
 	}
-	t.handlers = nil
-	t.closed = true
+	if n := t.drop(); n > 0 {
+		t.countDrops(n)
+		t.unqueue(n)
+		return fmt.Errorf("%w: %d on topic %q", ErrUndelivered, n, t.name)
+	}
 	return nil
 }
+
+// drop empties the queue of a closed topic and returns the number of messages it held.
+func (t *topic) drop() int {
+	n := 0
+	for {
+		select {
+		case m := <-t.queue:
+			m.done()
+			n++
+		default:
+			return n
+		}
+	}
+}
+
+// closeOpen closes the topic and reports whether it was open. The sequence of its Events
+// restarts if resetSeq is set.
+func (t *topic) closeOpen(resetSeq bool) bool {
+	t.mu.Lock()
+	if resetSeq {
+		// Also when the topic was closed by Unsubscribe, which keeps the sequence.
+		t.seq.Store(0)
+	}
+	if t.closed {
+		t.mu.Unlock()
+		return false
+	}
+	subs := t.subs.clear()
+	t.buffered = nil
+	t.closed = true
+	t.notify()
+	if t.done != nil {
+		close(t.done)
+	}
+	t.mu.Unlock()
+	stopAll(subs)
+	t.p.logEvent("close", t.name, 0)
+	t.p.topicsChanged(-1)
+	t.p.systemEvent(TopicClosed, t.name, 0)
+	return true
+}
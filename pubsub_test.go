@@ -1,7 +1,16 @@
 package pubsub
 
 import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestPubSub(t *testing.T) {
@@ -83,14 +92,17 @@ func TestPubSub(t *testing.T) {
 	}
 
 	err = ps.Publish(topic, "test message")
-	if err != nil {
-		t.Errorf("Publish returned an error: %s", err.Error())
+	if !errors.Is(err, ErrShutdown) {
+		t.Errorf("expected ErrShutdown publishing after Shutdown, got %v", err)
 	}
 
 	if handlerCalled {
 		t.Error("Handler was called after shutting down the PubSub")
 	}
 
+	// The remaining checks need a PubSub that isn't shut down.
+	ps = New()
+
 	// Test unsubscribing from a non-existent topic
 	err = ps.Unsubscribe("nonExistentTopic")
 	if err != nil {
@@ -109,3 +121,1677 @@ func TestPubSub(t *testing.T) {
 		t.Errorf("Publish returned an error for a non-existent topic: %s", err.Error())
 	}
 }
+
+func TestSubscribeStateful2(t *testing.T) {
+	ps := New()
+	topic := "events"
+	const total = 1000
+
+	// The projection is the state the snapshot is taken from.
+	var stateMu sync.Mutex
+	var state []any
+	err := ps.Subscribe(topic, func(args ...any) {
+		stateMu.Lock()
+		state = append(state, args[0])
+		stateMu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < total; i++ {
+			if i == total/4 {
+				close(started)
+			}
+			if err := ps.Publish(topic, i); err != nil {
+				t.Errorf("Publish returned an error: %s", err.Error())
+			}
+		}
+	}()
+
+	<-started
+	var received []any
+	err = ps.SubscribeStateful2(topic, func() []any {
+		stateMu.Lock()
+		defer stateMu.Unlock()
+		return append([]any(nil), state...)
+	}, func(args ...any) {
+		received = append(received, args...)
+	})
+	if err != nil {
+		t.Fatalf("SubscribeStateful2 returned an error: %s", err.Error())
+	}
+	<-done
+
+	if len(received) != total {
+		t.Fatalf("expected %d values, got %d", total, len(received))
+	}
+	for i, v := range received {
+		if v != i {
+			t.Fatalf("expected value %d at position %d, got %v", i, i, v)
+		}
+	}
+}
+
+func TestSubscribeStatefulBusyTopic(t *testing.T) {
+	ps := New()
+	// Slow handlers make the deliveries of the publishers overlap.
+	if err := ps.Subscribe("events", func(args ...any) { time.Sleep(time.Millisecond) }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if err := ps.Publish("events"); err != nil {
+					t.Errorf("Publish returned an error: %s", err.Error())
+					return
+				}
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(stop)
+
+	// The publishers never let the deliveries in progress drop to zero on their own.
+	withinSecond(t, func() {
+		if err := ps.SubscribeStateful2("events", func() []any { return nil }, func(args ...any) {}); err != nil {
+			t.Errorf("SubscribeStateful2 returned an error: %s", err.Error())
+		}
+	})
+}
+
+func TestOperationString(t *testing.T) {
+	tests := []struct {
+		op   Operation
+		name string
+	}{
+		{Subscribe, "Subscribe"},
+		{SubscribeOnce, "SubscribeOnce"},
+		{SubscribeOnceEach, "SubscribeOnceEach"},
+		{Publish, "Publish"},
+		{TryPublish, "TryPublish"},
+		{Unsubscribe, "Unsubscribe"},
+		{UnsubscribeAll, "UnsubscribeAll"},
+		{CloseTopic, "CloseTopic"},
+		{Shutdown, "Shutdown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if s := tt.op.String(); s != tt.name {
+				t.Errorf("expected String() to return %q, got %q", tt.name, s)
+			}
+			op, err := ParseOperation(tt.name)
+			if err != nil {
+				t.Fatalf("ParseOperation returned an error: %s", err.Error())
+			}
+			if op != tt.op {
+				t.Errorf("expected ParseOperation to return %d, got %d", tt.op, op)
+			}
+		})
+	}
+
+	if s := Operation(42).String(); s != "Operation(42)" {
+		t.Errorf("expected String() to return %q for an out of range operation, got %q", "Operation(42)", s)
+	}
+	if _, err := ParseOperation("Explode"); err == nil {
+		t.Error("expected ParseOperation to return an error for an unknown name")
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	ps := New()
+	topic := "testTopic"
+	var received []any
+	err := ps.Subscribe(topic, func(args ...any) {
+		received = append(received, args[0])
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Pause(topic); err != nil {
+		t.Fatalf("Pause returned an error: %s", err.Error())
+	}
+	if err := ps.Publish(topic, 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(received) != 0 {
+		t.Errorf("Handler was called while the topic was paused: %v", received)
+	}
+
+	if err := ps.Resume(topic); err != nil {
+		t.Fatalf("Resume returned an error: %s", err.Error())
+	}
+	if err := ps.Publish(topic, 2); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(received) != 1 || received[0] != 2 {
+		t.Errorf("expected only the message published after Resume, got %v", received)
+	}
+}
+
+func TestPauseResumeBuffered(t *testing.T) {
+	ps := New(WithPauseBuffer())
+	topic := "testTopic"
+	var received []any
+	err := ps.Subscribe(topic, func(args ...any) {
+		received = append(received, args[0])
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Pause(topic); err != nil {
+		t.Fatalf("Pause returned an error: %s", err.Error())
+	}
+	for i := 1; i <= 3; i++ {
+		if err := ps.Publish(topic, i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if len(received) != 0 {
+		t.Errorf("Handler was called while the topic was paused: %v", received)
+	}
+
+	if err := ps.Resume(topic); err != nil {
+		t.Fatalf("Resume returned an error: %s", err.Error())
+	}
+	if len(received) != 3 || received[0] != 1 || received[1] != 2 || received[2] != 3 {
+		t.Errorf("expected the buffered messages in order, got %v", received)
+	}
+}
+
+func TestPauseResumeBufferedOrder(t *testing.T) {
+	ps := New(WithPauseBuffer())
+	topic := "testTopic"
+	var received []any
+	err := ps.Subscribe(topic, func(args ...any) {
+		received = append(received, args[0])
+		// A message published while the buffer is flushed goes after the buffered ones.
+		if args[0] == 1 {
+			if err := ps.Publish(topic, "new"); err != nil {
+				t.Errorf("Publish returned an error: %s", err.Error())
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Pause(topic); err != nil {
+		t.Fatalf("Pause returned an error: %s", err.Error())
+	}
+	for i := 1; i <= 3; i++ {
+		if err := ps.Publish(topic, i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if err := ps.Resume(topic); err != nil {
+		t.Fatalf("Resume returned an error: %s", err.Error())
+	}
+	if want := []any{1, 2, 3, "new"}; !reflect.DeepEqual(received, want) {
+		t.Errorf("expected %v, got %v", want, received)
+	}
+}
+
+func TestPauseResumeConcurrentPublish(t *testing.T) {
+	ps := New(WithPauseBuffer(), WithPauseBufferLimit(0))
+	topic := "testTopic"
+	const buffered, concurrent = 500, 500
+	var mu sync.Mutex
+	var received []int
+	if err := ps.Subscribe(topic, func(args ...any) {
+		mu.Lock()
+		received = append(received, args[0].(int))
+		mu.Unlock()
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Pause(topic); err != nil {
+		t.Fatalf("Pause returned an error: %s", err.Error())
+	}
+	for i := 0; i < buffered; i++ {
+		if err := ps.Publish(topic, i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := buffered; i < buffered+concurrent; i++ {
+			if err := ps.Publish(topic, i); err != nil {
+				t.Errorf("Publish returned an error: %s", err.Error())
+			}
+		}
+	}()
+	if err := ps.Resume(topic); err != nil {
+		t.Fatalf("Resume returned an error: %s", err.Error())
+	}
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != buffered+concurrent {
+		t.Fatalf("expected %d messages, got %d", buffered+concurrent, len(received))
+	}
+	for i, n := range received {
+		if n != i {
+			t.Fatalf("expected the messages in publish order, got %d at %d", n, i)
+		}
+	}
+}
+
+func TestPauseBufferLimit(t *testing.T) {
+	var dropped []any
+	ps := New(WithPauseBuffer(), WithPauseBufferLimit(2), WithOverflow(OverflowBlock, func(topic string, args []any) {
+		dropped = append(dropped, args[0])
+	}))
+	topic := "testTopic"
+	var received []any
+	if err := ps.Subscribe(topic, func(args ...any) { received = append(received, args[0]) }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Pause(topic); err != nil {
+		t.Fatalf("Pause returned an error: %s", err.Error())
+	}
+	for i := 1; i <= 4; i++ {
+		if err := ps.Publish(topic, i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if err := ps.Resume(topic); err != nil {
+		t.Fatalf("Resume returned an error: %s", err.Error())
+	}
+	if want := []any{1, 2}; !reflect.DeepEqual(received, want) {
+		t.Errorf("expected the first %v to be buffered, got %v", want, received)
+	}
+	if want := []any{3, 4}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("expected %v to be dropped, got %v", want, dropped)
+	}
+	if n := ps.DroppedCount(topic); n != 2 {
+		t.Errorf("expected 2 dropped messages, got %d", n)
+	}
+}
+
+func TestSubscribeFiltered(t *testing.T) {
+	ps := New()
+	topic := "testTopic"
+	var received []any
+	cancel, err := ps.SubscribeFiltered(topic, func(args ...any) bool {
+		return len(args) > 0 && args[0] == "wanted"
+	}, func(args ...any) {
+		received = append(received, args[1])
+	})
+	if err != nil {
+		t.Fatalf("SubscribeFiltered returned an error: %s", err.Error())
+	}
+
+	for i, kind := range []string{"wanted", "other", "wanted", "other"} {
+		if err := ps.Publish(topic, kind, i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if len(received) != 2 || received[0] != 0 || received[1] != 2 {
+		t.Errorf("expected only the matching messages 0 and 2, got %v", received)
+	}
+
+	cancel()
+	if err := ps.Publish(topic, "wanted", 4); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(received) != 2 {
+		t.Errorf("Handler was called after cancel: %v", received)
+	}
+}
+
+func TestSubscribeOnceKeepsOtherHandlers(t *testing.T) {
+	ps := New()
+	topic := "testTopic"
+	onceCalls, calls := 0, 0
+	if err := ps.Subscribe(topic, func(args ...any) { calls++ }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if _, err := ps.SubscribeOnce(topic, func(args ...any) { onceCalls++ }); err != nil {
+		t.Fatalf("SubscribeOnce returned an error: %s", err.Error())
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := ps.Publish(topic, i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if onceCalls != 1 {
+		t.Errorf("expected the once handler to be called once, got %d", onceCalls)
+	}
+	if calls != 3 {
+		t.Errorf("expected the regular handler to be called 3 times, got %d", calls)
+	}
+}
+
+func TestSubscribeOnceCancel(t *testing.T) {
+	ps := New()
+	topic := "testTopic"
+	cancelled, fired, kept := 0, 0, 0
+	if err := ps.Subscribe(topic, func(args ...any) { kept++ }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	cancel, err := ps.SubscribeOnce(topic, func(args ...any) { cancelled++ })
+	if err != nil {
+		t.Fatalf("SubscribeOnce returned an error: %s", err.Error())
+	}
+	cancelEach, err := ps.SubscribeOnceEach(topic, func(args ...any) { cancelled++ })
+	if err != nil {
+		t.Fatalf("SubscribeOnceEach returned an error: %s", err.Error())
+	}
+	cancelFired, err := ps.SubscribeOnce(topic, func(args ...any) { fired++ })
+	if err != nil {
+		t.Fatalf("SubscribeOnce returned an error: %s", err.Error())
+	}
+
+	// Cancelling before the first publish removes only those handlers.
+	cancel()
+	cancelEach()
+	if err := ps.Publish(topic); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	// Cancelling after the handler fired is a no-op.
+	cancelFired()
+	cancel()
+	if err := ps.Publish(topic); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+
+	if cancelled != 0 {
+		t.Errorf("expected the cancelled handlers not to be called, got %d calls", cancelled)
+	}
+	if fired != 1 {
+		t.Errorf("expected the once handler to be called once, got %d", fired)
+	}
+	if kept != 2 {
+		t.Errorf("expected the regular handler to be called twice, got %d", kept)
+	}
+}
+
+func TestSubscribeOnceIntermixedStress(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"sync":    nil,
+		"async":   {WithAsync(64)},
+		"workers": {WithWorkers(4)},
+	} {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			ps := New(opts...)
+			defer ps.Shutdown()
+			const handlers, publishers, perPublisher = 300, 8, 50
+			topic := "testTopic"
+
+			counts := make([]atomic.Int32, handlers)
+			var permanent []SubscriptionID
+			for i := 0; i < handlers; i++ {
+				i := i
+				handler := func(args ...any) { counts[i].Add(1) }
+				var err error
+				switch i % 3 {
+				case 0:
+					var id SubscriptionID
+					id, err = ps.SubscribeWithID(topic, handler)
+					permanent = append(permanent, id)
+				case 1:
+					_, err = ps.SubscribeOnce(topic, handler)
+				case 2:
+					_, err = ps.SubscribeOnceEach(topic, handler)
+				}
+				if err != nil {
+					t.Fatalf("subscribing handler %d returned an error: %s", i, err.Error())
+				}
+			}
+
+			var wg sync.WaitGroup
+			for i := 0; i < publishers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < perPublisher; j++ {
+						if err := ps.Publish(topic, j); err != nil {
+							t.Errorf("Publish returned an error: %s", err.Error())
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := ps.Drain(ctx); err != nil {
+				t.Fatalf("Drain returned an error: %s", err.Error())
+			}
+
+			for i := range counts {
+				want := int32(1)
+				if i%3 == 0 {
+					want = publishers * perPublisher
+				}
+				if n := counts[i].Load(); n != want {
+					t.Fatalf("expected handler %d to be called %d times, got %d", i, want, n)
+				}
+			}
+			top, _ := ps.(*pubsub).getTopic(topic)
+			top.mu.Lock()
+			var surviving []SubscriptionID
+			for _, sub := range top.subs.all() {
+				surviving = append(surviving, sub.id)
+			}
+			top.mu.Unlock()
+			if !reflect.DeepEqual(surviving, permanent) {
+				t.Fatalf("expected exactly the permanent handlers %v to survive in order, got %v", permanent, surviving)
+			}
+		})
+	}
+}
+
+func TestDeadLetterOnPanic(t *testing.T) {
+	ps := New(WithDeadLetter("dead"))
+	var dead []any
+	err := ps.Subscribe("dead", func(args ...any) {
+		dead = args
+		panic("dead-letter handlers failing must not loop")
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	err = ps.Subscribe("testTopic", func(args ...any) {
+		panic("boom")
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	called := false
+	err = ps.Subscribe("testTopic", func(args ...any) {
+		called = true
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("testTopic", "payload", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+
+	if len(dead) != 3 || dead[0] != "payload" || dead[1] != 1 {
+		t.Fatalf("expected the original payload followed by an error, got %v", dead)
+	}
+	if err, ok := dead[2].(error); !ok || !errors.Is(err, ErrHandlerPanic) {
+		t.Errorf("expected ErrHandlerPanic, got %v", dead[2])
+	}
+	if !called {
+		t.Error("Handler after the panicking one was not called")
+	}
+}
+
+func benchmarkPublishManyTopics(b *testing.B, ps PubSub) {
+	const numTopics = 1024
+	topics := make([]string, numTopics)
+	for i := range topics {
+		topics[i] = "topic" + strconv.Itoa(i)
+		if err := ps.Subscribe(topics[i], func(args ...any) {}); err != nil {
+			b.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	var worker atomic.Int32
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		// Start each worker on a different topic.
+		i := int(worker.Add(1)) * 97
+		for pb.Next() {
+			// Mix in topic creation and pausing alongside the publishes.
+			if i%16 == 0 {
+				_ = ps.Pause(topics[i%numTopics] + "/paused")
+			}
+			_ = ps.Publish(topics[i%numTopics], i)
+			i++
+		}
+	})
+}
+
+func BenchmarkPublishSingleLock(b *testing.B) {
+	benchmarkPublishManyTopics(b, New(WithShards(1)))
+}
+
+func BenchmarkPublishSharded(b *testing.B) {
+	benchmarkPublishManyTopics(b, New())
+}
+
+func TestSubscribeAfterUnsubscribe(t *testing.T) {
+	ps := New()
+	topic := "testTopic"
+	if err := ps.Subscribe(topic, func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Unsubscribe(topic); err != nil {
+		t.Fatalf("Unsubscribe returned an error: %s", err.Error())
+	}
+
+	// Unsubscribe closes the topic, so it refuses new handlers like one closed with CloseTopic.
+	if err := ps.Subscribe(topic, func(args ...any) {}); !errors.Is(err, ErrTopicClosed) {
+		t.Errorf("expected ErrTopicClosed when subscribing after Unsubscribe, got %v", err)
+	}
+}
+
+func TestWaitForSubscribers(t *testing.T) {
+	ps := New()
+	topic := "testTopic"
+
+	go func() {
+		for i := 0; i < 2; i++ {
+			time.Sleep(10 * time.Millisecond)
+			if err := ps.Subscribe(topic, func(args ...any) {}); err != nil {
+				t.Errorf("Subscribe returned an error: %s", err.Error())
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ps.WaitForSubscribers(ctx, topic, 2); err != nil {
+		t.Fatalf("WaitForSubscribers returned an error: %s", err.Error())
+	}
+}
+
+func TestWaitForSubscribersCancelled(t *testing.T) {
+	ps := New()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := ps.WaitForSubscribers(ctx, "testTopic", 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestMaxSubscribers(t *testing.T) {
+	ps := New(WithMaxSubscribers(2))
+	var ids []SubscriptionID
+	for i := 0; i < 2; i++ {
+		id, err := ps.SubscribeWithID("testTopic", func(args ...any) {})
+		if err != nil {
+			t.Fatalf("SubscribeWithID returned an error below the limit: %s", err.Error())
+		}
+		ids = append(ids, id)
+	}
+	if err := ps.Subscribe("testTopic", func(args ...any) {}); !errors.Is(err, ErrTooManySubscribers) {
+		t.Errorf("expected ErrTooManySubscribers past the limit, got %v", err)
+	}
+	if _, err := ps.SubscribeOnce("testTopic", func(args ...any) {}); !errors.Is(err, ErrTooManySubscribers) {
+		t.Errorf("expected ErrTooManySubscribers from SubscribeOnce past the limit, got %v", err)
+	}
+
+	// The limit applies per topic.
+	if err := ps.Subscribe("otherTopic", func(args ...any) {}); err != nil {
+		t.Errorf("Subscribe returned an error on another topic: %s", err.Error())
+	}
+
+	// Removing a handler makes room again.
+	if err := ps.UnsubscribeByID("testTopic", ids[0]); err != nil {
+		t.Fatalf("UnsubscribeByID returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("testTopic", func(args ...any) {}); err != nil {
+		t.Errorf("Subscribe returned an error after removing a handler: %s", err.Error())
+	}
+}
+
+func TestCloseSubtree(t *testing.T) {
+	ps := New()
+	topics := []string{"tenant1", "tenant1.orders", "tenant1.orders.created", "tenant10", "tenant10.orders", "tenant2.orders"}
+	calls := make(map[string]int)
+	for _, topic := range topics {
+		topic := topic
+		if err := ps.Subscribe(topic, func(args ...any) { calls[topic]++ }); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	n, err := ps.CloseSubtree("tenant1")
+	if err != nil {
+		t.Fatalf("CloseSubtree returned an error: %s", err.Error())
+	}
+	if n != 3 {
+		t.Errorf("expected 3 topics to be closed, got %d", n)
+	}
+
+	for _, topic := range topics {
+		if err := ps.Publish(topic); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	want := map[string]int{"tenant10": 1, "tenant10.orders": 1, "tenant2.orders": 1}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected only topics outside the subtree to deliver, got %v", calls)
+	}
+
+	// Closing again doesn't count the already closed topics.
+	if n, _ := ps.CloseSubtree("tenant1"); n != 0 {
+		t.Errorf("expected 0 topics to be closed the second time, got %d", n)
+	}
+}
+
+func TestAsyncTryPublishWouldBlock(t *testing.T) {
+	ps := New(WithAsync(2))
+	topic := "testTopic"
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var received []any
+	err := ps.Subscribe(topic, func(args ...any) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		mu.Lock()
+		received = append(received, args[0])
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	// The first message occupies the handler, the next two fill the queue.
+	if err := ps.Publish(topic, 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	<-started
+	for i := 2; i <= 3; i++ {
+		if err := ps.TryPublish(topic, i); err != nil {
+			t.Fatalf("TryPublish returned an error with room in the queue: %s", err.Error())
+		}
+	}
+	if err := ps.TryPublish(topic, 4); !errors.Is(err, ErrWouldBlock) {
+		t.Fatalf("expected ErrWouldBlock with a full queue, got %v", err)
+	}
+
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		if err := ps.Publish(topic, 4); err != nil {
+			t.Errorf("Publish returned an error: %s", err.Error())
+		}
+	}()
+	select {
+	case <-published:
+		t.Fatal("Publish returned while the queue was full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-published
+	if err := ps.Publish(topic, 5); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(received)
+		mu.Unlock()
+		if n == 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 5 messages to be delivered, got %d", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i, v := range received {
+		if v != i+1 {
+			t.Errorf("expected message %d at position %d, got %v", i+1, i, v)
+		}
+	}
+}
+
+func TestHasTopicIsClosed(t *testing.T) {
+	ps := New()
+	if err := ps.Subscribe("open", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("closed", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.CloseTopic("closed"); err != nil {
+		t.Fatalf("CloseTopic returned an error: %s", err.Error())
+	}
+
+	tests := []struct {
+		topic    string
+		hasTopic bool
+		isClosed bool
+	}{
+		{"neverCreated", false, false},
+		{"open", true, false},
+		{"closed", false, true},
+	}
+	for _, tt := range tests {
+		if got := ps.HasTopic(tt.topic); got != tt.hasTopic {
+			t.Errorf("HasTopic(%q) = %t, expected %t", tt.topic, got, tt.hasTopic)
+		}
+		if got := ps.IsClosed(tt.topic); got != tt.isClosed {
+			t.Errorf("IsClosed(%q) = %t, expected %t", tt.topic, got, tt.isClosed)
+		}
+	}
+}
+
+func TestUnsubscribeByID(t *testing.T) {
+	ps := New()
+	topic := "testTopic"
+	removed, kept := 0, 0
+	id, err := ps.SubscribeWithID(topic, func(args ...any) { removed++ })
+	if err != nil {
+		t.Fatalf("SubscribeWithID returned an error: %s", err.Error())
+	}
+	otherID, err := ps.SubscribeWithID(topic, func(args ...any) { kept++ })
+	if err != nil {
+		t.Fatalf("SubscribeWithID returned an error: %s", err.Error())
+	}
+	if id == otherID {
+		t.Fatalf("expected distinct IDs, got %d twice", id)
+	}
+
+	if err := ps.Publish(topic); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if err := ps.UnsubscribeByID(topic, id); err != nil {
+		t.Fatalf("UnsubscribeByID returned an error: %s", err.Error())
+	}
+	if err := ps.Publish(topic); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if removed != 1 {
+		t.Errorf("expected the removed handler to be called once, got %d", removed)
+	}
+	if kept != 2 {
+		t.Errorf("expected the other handler to be called twice, got %d", kept)
+	}
+
+	// Unknown IDs and topics are a no-op.
+	if err := ps.UnsubscribeByID(topic, id); err != nil {
+		t.Errorf("UnsubscribeByID returned an error for a removed ID: %s", err.Error())
+	}
+	if err := ps.UnsubscribeByID("nonExistentTopic", otherID); err != nil {
+		t.Errorf("UnsubscribeByID returned an error for a non-existent topic: %s", err.Error())
+	}
+}
+
+func TestHandlerOrder(t *testing.T) {
+	ps := New()
+	topic := "testTopic"
+	var order []string
+	subscribe := func(name string) {
+		t.Helper()
+		if err := ps.Subscribe(topic, func(args ...any) { order = append(order, name) }); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+	subscribe("A")
+	if _, err := ps.SubscribeOnce(topic, func(args ...any) { order = append(order, "once") }); err != nil {
+		t.Fatalf("SubscribeOnce returned an error: %s", err.Error())
+	}
+	subscribe("B")
+	subscribe("C")
+
+	for i := 0; i < 3; i++ {
+		if err := ps.Publish(topic, i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+
+	// Removing the once handler keeps the others in subscription order.
+	want := []string{"A", "once", "B", "C", "A", "B", "C", "A", "B", "C"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("expected handlers to be called in order %v, got %v", want, order)
+	}
+}
+
+func TestPublishIfSubscribed(t *testing.T) {
+	ps := New()
+	topic := "testTopic"
+	built := 0
+	build := func() []any {
+		built++
+		return []any{"payload"}
+	}
+
+	delivered, err := ps.PublishIfSubscribed(topic, build)
+	if err != nil {
+		t.Fatalf("PublishIfSubscribed returned an error: %s", err.Error())
+	}
+	if delivered || built != 0 {
+		t.Errorf("expected no delivery and no build without subscribers, got delivered=%t built=%d", delivered, built)
+	}
+
+	var received []any
+	if err := ps.Subscribe(topic, func(args ...any) { received = args }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	delivered, err = ps.PublishIfSubscribed(topic, build)
+	if err != nil {
+		t.Fatalf("PublishIfSubscribed returned an error: %s", err.Error())
+	}
+	if !delivered || built != 1 {
+		t.Errorf("expected delivery and one build with a subscriber, got delivered=%t built=%d", delivered, built)
+	}
+	if len(received) != 1 || received[0] != "payload" {
+		t.Errorf("expected the built payload to be delivered, got %v", received)
+	}
+
+	if err := ps.Unsubscribe(topic); err != nil {
+		t.Fatalf("Unsubscribe returned an error: %s", err.Error())
+	}
+	if delivered, _ := ps.PublishIfSubscribed(topic, build); delivered || built != 1 {
+		t.Errorf("expected no delivery and no build after Unsubscribe, got delivered=%t built=%d", delivered, built)
+	}
+}
+
+func TestPublishIfSubscribedRouted(t *testing.T) {
+	ps := New(WithGlobalOrder(true), WithStrictTopics())
+	if err := ps.Alias("from", "to"); err != nil {
+		t.Fatalf("Alias returned an error: %s", err.Error())
+	}
+	var received []any
+	if err := ps.Subscribe("to", func(args ...any) { received = args }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	var tapped []string
+	remove := ps.(*pubsub).addTap(func(topic string, args []any) { tapped = append(tapped, topic) })
+	defer remove()
+
+	delivered, err := ps.PublishIfSubscribed(" from ", func() []any { return []any{"payload"} })
+	if err != nil {
+		t.Fatalf("PublishIfSubscribed returned an error: %s", err.Error())
+	}
+	if !delivered {
+		t.Error("expected the message to be delivered through the alias")
+	}
+	if len(received) != 1 || received[0] != "payload" {
+		t.Errorf("expected the aliased topic to receive the payload, got %v", received)
+	}
+	if want := []string{"from"}; !reflect.DeepEqual(tapped, want) {
+		t.Errorf("expected taps to see %v, got %v", want, tapped)
+	}
+	if ps.HasTopic("from") {
+		t.Error("expected no topic to be created for the alias")
+	}
+}
+
+func TestNilHandler(t *testing.T) {
+	ps := New()
+	topic := "testTopic"
+
+	tests := []struct {
+		name      string
+		subscribe func() error
+	}{
+		{"Subscribe", func() error { return ps.Subscribe(topic, nil) }},
+		{"SubscribeOnce", func() error { _, err := ps.SubscribeOnce(topic, nil); return err }},
+		{"SubscribeOnceEach", func() error { _, err := ps.SubscribeOnceEach(topic, nil); return err }},
+		{"SubscribeStateful2", func() error { return ps.SubscribeStateful2(topic, func() []any { return nil }, nil) }},
+		{"SubscribeKeyed", func() error {
+			return ps.SubscribeKeyed(topic, func(args ...any) string { return "" }, 1, nil)
+		}},
+		{"SubscribeReliable", func() error { return ps.SubscribeReliable(topic, nil) }},
+		{"SubscribeFiltered", func() error {
+			_, err := ps.SubscribeFiltered(topic, nil, nil)
+			return err
+		}},
+		{"SubscribeMany", func() error {
+			_, err := ps.SubscribeMany([]string{topic}, nil)
+			return err
+		}},
+		{"SubscribeMerged", func() error {
+			_, err := ps.SubscribeMerged([]string{topic}, nil)
+			return err
+		}},
+		{"SubscribeWithID", func() error {
+			_, err := ps.SubscribeWithID(topic, nil)
+			return err
+		}},
+		{"Respond", func() error { return ps.Respond(topic, nil) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.subscribe(); !errors.Is(err, ErrNilHandler) {
+				t.Errorf("expected ErrNilHandler, got %v", err)
+			}
+		})
+	}
+
+	// Nothing was registered, so publishing doesn't panic.
+	if err := ps.Publish(topic, "test message"); err != nil {
+		t.Errorf("Publish returned an error: %s", err.Error())
+	}
+}
+
+func TestArgCopy(t *testing.T) {
+	for _, copyArgs := range []bool{false, true} {
+		var opts []Option
+		if copyArgs {
+			opts = append(opts, WithArgCopy())
+		}
+		ps := New(opts...)
+		topic := "testTopic"
+		if err := ps.Subscribe(topic, func(args ...any) { args[0] = "mutated" }); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+		var seen any
+		if err := ps.Subscribe(topic, func(args ...any) { seen = args[0] }); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+
+		if err := ps.Publish(topic, "original"); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+		want := "mutated"
+		if copyArgs {
+			want = "original"
+		}
+		if seen != want {
+			t.Errorf("copyArgs=%t: expected the second handler to see %q, got %v", copyArgs, want, seen)
+		}
+	}
+}
+
+func TestClosed(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"sync":  nil,
+		"async": {WithAsync(4)},
+	} {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			ps := New(opts...)
+			if ps.Closed() {
+				t.Fatal("expected a new PubSub not to be closed")
+			}
+			if err := ps.Subscribe("topic", func(args ...any) {}); err != nil {
+				t.Fatalf("Subscribe returned an error: %s", err.Error())
+			}
+			if err := ps.ShutdownExcept(); err != nil {
+				t.Fatalf("ShutdownExcept returned an error: %s", err.Error())
+			}
+			if ps.Closed() {
+				t.Fatal("expected ShutdownExcept not to close the PubSub")
+			}
+
+			if err := ps.Shutdown(); err != nil {
+				t.Fatalf("Shutdown returned an error: %s", err.Error())
+			}
+			if !ps.Closed() {
+				t.Fatal("expected the PubSub to be closed after Shutdown")
+			}
+			if !ps.Namespace("ns").Closed() {
+				t.Fatal("expected a namespace of a closed PubSub to be closed")
+			}
+
+			ops := map[string]func() error{
+				"Subscribe":  func() error { return ps.Subscribe("topic", func(args ...any) {}) },
+				"Publish":    func() error { return ps.Publish("topic") },
+				"TryPublish": func() error { return ps.TryPublish("other") },
+				"SubscribeMany": func() error {
+					_, err := ps.SubscribeMany([]string{"a", "b"}, func(args ...any) {})
+					return err
+				},
+				"PublishIfSubscribed": func() error {
+					_, err := ps.PublishIfSubscribed("topic", func() []any { return nil })
+					return err
+				},
+				"PublishAfter": func() error {
+					_, err := ps.PublishAfter("topic", time.Second)
+					return err
+				},
+				"Pause":        func() error { return ps.Pause("paused") },
+				"DeclareTopic": func() error { return ps.DeclareTopic("declared") },
+				"WaitForSubscribers": func() error {
+					return ps.WaitForSubscribers(context.Background(), "waited", 1)
+				},
+			}
+			for op, fn := range ops {
+				if err := fn(); !errors.Is(err, ErrShutdown) {
+					t.Errorf("expected ErrShutdown from %s after Shutdown, got %v", op, err)
+				}
+			}
+			if topics := ps.Topics(); len(topics) != 0 {
+				t.Errorf("expected no topics to be created after Shutdown, got %v", topics)
+			}
+
+			if err := ps.Reset(); err != nil {
+				t.Fatalf("Reset returned an error: %s", err.Error())
+			}
+			if ps.Closed() {
+				t.Fatal("expected Reset to reopen the PubSub")
+			}
+			if err := ps.Subscribe("topic", func(args ...any) {}); err != nil {
+				t.Fatalf("Subscribe returned an error after Reset: %s", err.Error())
+			}
+			_ = ps.Shutdown()
+		})
+	}
+}
+
+func TestShutdownExcept(t *testing.T) {
+	ps := New()
+	topics := []string{"control", "orders", "invoices"}
+	calls := make(map[string]int)
+	for _, topic := range topics {
+		topic := topic
+		if err := ps.Subscribe(topic, func(args ...any) { calls[topic]++ }); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	if err := ps.ShutdownExcept("control"); err != nil {
+		t.Fatalf("ShutdownExcept returned an error: %s", err.Error())
+	}
+	for _, topic := range topics {
+		if err := ps.Publish(topic); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if want := map[string]int{"control": 1}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected only the retained topic to deliver, got %v", calls)
+	}
+	if want := []string{"control"}; !reflect.DeepEqual(ps.Topics(), want) {
+		t.Errorf("expected topics %v, got %v", want, ps.Topics())
+	}
+	if !ps.IsClosed("orders") || !ps.IsClosed("invoices") {
+		t.Errorf("expected the other topics to be closed")
+	}
+	if err := ps.Subscribe("orders", func(args ...any) {}); !errors.Is(err, ErrTopicClosed) {
+		t.Errorf("expected ErrTopicClosed subscribing to a closed topic, got %v", err)
+	}
+}
+
+func TestShutdownExceptAsync(t *testing.T) {
+	ps := New(WithAsync(4))
+	defer ps.Shutdown()
+	got := make(chan any, 1)
+	if err := ps.Subscribe("control", func(args ...any) { got <- args[0] }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("orders", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.ShutdownExcept("control"); err != nil {
+		t.Fatalf("ShutdownExcept returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("control", "reload"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	select {
+	case v := <-got:
+		if v != "reload" {
+			t.Errorf("expected reload, got %v", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the retained topic did not deliver")
+	}
+}
+
+func TestShutdownJoinsErrors(t *testing.T) {
+	ps := New(WithAsync(2))
+	topics := []string{"first", "second"}
+
+	started := make(chan struct{}, len(topics))
+	release := make(chan struct{})
+	defer close(release)
+	for _, topic := range topics {
+		err := ps.Subscribe(topic, func(args ...any) {
+			started <- struct{}{}
+			<-release
+		})
+		if err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	// The first message of each topic occupies its handler, the second stays queued.
+	for _, topic := range topics {
+		if err := ps.Publish(topic, 1); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	for range topics {
+		<-started
+	}
+	for _, topic := range topics {
+		if err := ps.Publish(topic, 2); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+
+	err := ps.Shutdown()
+	if !errors.Is(err, ErrUndelivered) {
+		t.Fatalf("expected ErrUndelivered, got %v", err)
+	}
+	for _, topic := range topics {
+		if !strings.Contains(err.Error(), strconv.Quote(topic)) {
+			t.Errorf("expected the error of topic %q in %q", topic, err.Error())
+		}
+	}
+}
+
+// withinSecond fails the test if fn doesn't return within a second, which here means it deadlocked.
+func withinSecond(t *testing.T, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("deadlocked")
+	}
+}
+
+// waitUntilWaiting returns once a goroutine waits on a sync.Cond in the function named fn.
+func waitUntilWaiting(fn string) {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		for _, stack := range strings.Split(string(buf[:n]), "\n\n") {
+			if strings.Contains(stack, "sync.(*Cond).Wait") && strings.Contains(stack, ")."+fn+"(") {
+				return
+			}
+		}
+		runtime.Gosched()
+	}
+}
+
+func TestReentrantHandlers(t *testing.T) {
+	ps := New()
+	var calls []string
+	record := func(name string) func(...any) {
+		return func(args ...any) {
+			calls = append(calls, name)
+		}
+	}
+
+	err := ps.Subscribe("first", func(args ...any) {
+		calls = append(calls, "first")
+		if args[0] != 1 {
+			return
+		}
+		// Subscribe, publish and unsubscribe on the same topic and on another one.
+		if err := ps.Subscribe("first", record("added")); err != nil {
+			t.Errorf("Subscribe returned an error: %s", err.Error())
+		}
+		if err := ps.Subscribe("second", record("second")); err != nil {
+			t.Errorf("Subscribe returned an error: %s", err.Error())
+		}
+		if err := ps.Publish("second"); err != nil {
+			t.Errorf("Publish returned an error: %s", err.Error())
+		}
+		if err := ps.Publish("first", 2); err != nil {
+			t.Errorf("Publish returned an error: %s", err.Error())
+		}
+		if err := ps.Unsubscribe("second"); err != nil {
+			t.Errorf("Unsubscribe returned an error: %s", err.Error())
+		}
+		if err := ps.Unsubscribe("first"); err != nil {
+			t.Errorf("Unsubscribe returned an error: %s", err.Error())
+		}
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	withinSecond(t, func() {
+		if err := ps.Publish("first", 1); err != nil {
+			t.Errorf("Publish returned an error: %s", err.Error())
+		}
+	})
+	want := []string{"first", "second", "first", "added"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+	if snapshot := ps.Snapshot(); snapshot["first"] != 0 || snapshot["second"] != 0 {
+		t.Errorf("expected all handlers to be removed, got %v", snapshot)
+	}
+}
+
+func TestReentrantPublishWhileSubscribingStateful(t *testing.T) {
+	ps := New()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	err := ps.Subscribe("testTopic", func(args ...any) {
+		if args[0] != 1 {
+			return
+		}
+		close(started)
+		<-release
+		// Publishing to the handler's own topic must not wait for the stateful
+		// subscriber, which itself waits for this delivery to finish.
+		if err := ps.Publish("testTopic", 2); err != nil {
+			t.Errorf("Publish returned an error: %s", err.Error())
+		}
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		if err := ps.Publish("testTopic", 1); err != nil {
+			t.Errorf("Publish returned an error: %s", err.Error())
+		}
+	}()
+	<-started
+
+	var received []any
+	subscribed := make(chan struct{})
+	go func() {
+		defer close(subscribed)
+		err := ps.SubscribeStateful2("testTopic", func() []any { return []any{"snapshot"} }, func(args ...any) {
+			received = append(received, args[0])
+		})
+		if err != nil {
+			t.Errorf("SubscribeStateful2 returned an error: %s", err.Error())
+		}
+	}()
+	withinSecond(t, func() { waitUntilWaiting("subscribeStateful") })
+	close(release)
+
+	withinSecond(t, func() {
+		<-published
+		<-subscribed
+	})
+	if len(received) != 1 || received[0] != "snapshot" {
+		t.Errorf("expected only the snapshot, got %v", received)
+	}
+}
+
+func BenchmarkPublishNoSubscribers(b *testing.B) {
+	// Calls through the PubSub interface always allocate the variadic args, since the
+	// compiler can't tell that they don't escape, so call the implementation directly.
+	ps := New().(*pubsub)
+	if err := ps.Subscribe("empty", func(args ...any) {}); err != nil {
+		b.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Unsubscribe("empty"); err != nil {
+		b.Fatalf("Unsubscribe returned an error: %s", err.Error())
+	}
+	value := 1000
+
+	for _, topic := range []string{"unknown", "empty"} {
+		b.Run(topic, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = ps.Publish(topic, "message", &value)
+			}
+		})
+	}
+}
+
+func TestPublishNoSubscribersDoesNotAllocate(t *testing.T) {
+	ps := New().(*pubsub)
+	value := 1000
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = ps.Publish("testTopic", "message", &value)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v per publish", allocs)
+	}
+}
+
+func TestUnsubscribeHandler(t *testing.T) {
+	ps := New()
+	topic := "testTopic"
+	removed, kept := 0, 0
+	removedHandler := func(args ...any) { removed++ }
+	keptHandler := func(args ...any) { kept++ }
+	for _, handler := range []func(...any){removedHandler, keptHandler} {
+		if err := ps.Subscribe(topic, handler); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	if err := ps.UnsubscribeHandler(topic, removedHandler); err != nil {
+		t.Fatalf("UnsubscribeHandler returned an error: %s", err.Error())
+	}
+	if err := ps.Publish(topic); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if removed != 0 {
+		t.Errorf("expected the removed handler not to be called, got %d calls", removed)
+	}
+	if kept != 1 {
+		t.Errorf("expected the other handler to be called once, got %d", kept)
+	}
+
+	// Handlers that aren't registered and unknown topics are a no-op.
+	if err := ps.UnsubscribeHandler(topic, removedHandler); err != nil {
+		t.Errorf("UnsubscribeHandler returned an error for a removed handler: %s", err.Error())
+	}
+	if err := ps.UnsubscribeHandler("nonExistentTopic", keptHandler); err != nil {
+		t.Errorf("UnsubscribeHandler returned an error for a non-existent topic: %s", err.Error())
+	}
+	if n := ps.Snapshot()[topic]; n != 1 {
+		t.Errorf("expected 1 handler left, got %d", n)
+	}
+}
+
+func TestSubscribeCloseRace(t *testing.T) {
+	p := New().(*pubsub)
+	const subscribers, perSubscriber = 4, 200
+
+	type attempt struct {
+		sub     *subscription
+		removed *atomic.Bool
+	}
+	var mu sync.Mutex
+	var attached []attempt
+	stop := make(chan struct{})
+	closerDone := make(chan struct{})
+	go func() {
+		defer close(closerDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			// Close the topic, then remove it so the next subscribe creates it again.
+			_ = p.CloseTopic("testTopic")
+			p.removeTopic("testTopic")
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(subscribers)
+	for i := 0; i < subscribers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perSubscriber; j++ {
+				removed := new(atomic.Bool)
+				sub := &subscription{handler: func(args ...any) {}, stop: func() { removed.Store(true) }}
+				_, err := p.subscribe("testTopic", sub)
+				if errors.Is(err, ErrTopicClosed) {
+					continue
+				}
+				if err != nil {
+					t.Errorf("subscribe returned an error: %s", err.Error())
+					return
+				}
+				mu.Lock()
+				attached = append(attached, attempt{sub, removed})
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	<-closerDone
+
+	// Every handler that was added is either still attached to the open topic or was
+	// removed when its topic closed, never left behind on a topic that is gone.
+	live := make(map[*subscription]bool)
+	if current, ok := p.getTopic("testTopic"); ok && !current.isClosed() {
+		for _, sub := range current.subs.all() {
+			live[sub] = true
+		}
+	}
+	for _, a := range attached {
+		if !live[a.sub] && !a.removed.Load() {
+			t.Fatal("a handler was attached to a topic that was closed and replaced")
+		}
+	}
+}
+
+func TestSubscribeWithClose(t *testing.T) {
+	ps := New()
+	var msgs, closes int
+	cancel, err := ps.SubscribeWithClose("topic", func(args ...any) { msgs++ }, func() { closes++ })
+	if err != nil {
+		t.Fatalf("SubscribeWithClose returned an error: %s", err.Error())
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := ps.Publish("topic"); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if msgs != 3 || closes != 0 {
+		t.Fatalf("expected 3 messages and no close, got %d and %d", msgs, closes)
+	}
+
+	cancel()
+	cancel()
+	if closes != 1 {
+		t.Fatalf("expected onClose to be called once on cancel, got %d", closes)
+	}
+	if err := ps.Publish("topic"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if msgs != 3 {
+		t.Fatalf("expected no message after cancel, got %d", msgs)
+	}
+}
+
+func TestSubscribeWithCloseOnCloseTopic(t *testing.T) {
+	ps := New()
+	var closes int
+	cancel, err := ps.SubscribeWithClose("topic", func(args ...any) {}, func() { closes++ })
+	if err != nil {
+		t.Fatalf("SubscribeWithClose returned an error: %s", err.Error())
+	}
+
+	if err := ps.CloseTopic("topic"); err != nil {
+		t.Fatalf("CloseTopic returned an error: %s", err.Error())
+	}
+	if closes != 1 {
+		t.Fatalf("expected onClose to be called on CloseTopic, got %d", closes)
+	}
+
+	// A failed subscription is never closed.
+	if _, err := ps.SubscribeWithClose("topic", func(args ...any) {}, func() { closes++ }); !errors.Is(err, ErrTopicClosed) {
+		t.Fatalf("expected ErrTopicClosed, got %v", err)
+	}
+
+	// Neither cancelling nor shutting down afterwards calls it again.
+	cancel()
+	if err := ps.Shutdown(); err != nil {
+		t.Fatalf("Shutdown returned an error: %s", err.Error())
+	}
+	if closes != 1 {
+		t.Fatalf("expected onClose to be called once, got %d", closes)
+	}
+}
+
+func TestSubscribeWithCloseOnShutdown(t *testing.T) {
+	ps := New(WithAsync(4))
+	closed := make(chan struct{})
+	if _, err := ps.SubscribeWithClose("topic", func(args ...any) {}, func() { close(closed) }); err != nil {
+		t.Fatalf("SubscribeWithClose returned an error: %s", err.Error())
+	}
+	if err := ps.Shutdown(); err != nil {
+		t.Fatalf("Shutdown returned an error: %s", err.Error())
+	}
+	select {
+	case <-closed:
+	default:
+		t.Fatal("expected onClose to be called by Shutdown")
+	}
+}
+
+func TestSubscribeOnceEachKeyed(t *testing.T) {
+	ps := New()
+	var fired []string
+	for _, name := range []string{"first", "second"} {
+		name := name
+		err := ps.SubscribeOnceEachKeyed("testTopic", "key", func(args ...any) {
+			fired = append(fired, name)
+		})
+		if err != nil {
+			t.Fatalf("SubscribeOnceEachKeyed returned an error: %s", err.Error())
+		}
+	}
+	err := ps.SubscribeOnceEachKeyed("testTopic", "other", func(args ...any) {
+		fired = append(fired, "other")
+	})
+	if err != nil {
+		t.Fatalf("SubscribeOnceEachKeyed returned an error: %s", err.Error())
+	}
+	if n := ps.Snapshot()["testTopic"]; n != 2 {
+		t.Fatalf("expected the same key to be subscribed once, got %d handlers", n)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := ps.Publish("testTopic"); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+
+	if !reflect.DeepEqual(fired, []string{"second", "other"}) {
+		t.Fatalf("expected the replacing handler and the other key to fire once each, got %v", fired)
+	}
+}
+
+// fillQueue subscribes a handler that blocks until release is closed and publishes
+// until the topic's queue of capacity 1 is full.
+func fillQueue(t *testing.T, ps PubSub, release chan struct{}, delivered *atomic.Int32) {
+	t.Helper()
+	started := make(chan struct{}, 1)
+	err := ps.Subscribe("testTopic", func(args ...any) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		delivered.Add(1)
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	// The first message blocks the worker, the second one fills the queue.
+	if err := ps.Publish("testTopic", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	<-started
+	if err := ps.Publish("testTopic", 2); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+}
+
+func TestPublishWait(t *testing.T) {
+	ps := New(WithAsync(1))
+	defer ps.Shutdown()
+	release := make(chan struct{})
+	var delivered atomic.Int32
+	fillQueue(t, ps, release, &delivered)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- ps.PublishWait(context.Background(), "testTopic", 3)
+	}()
+	select {
+	case err := <-result:
+		t.Fatalf("expected PublishWait to block while the queue is full, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("PublishWait returned an error: %s", err.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("PublishWait did not return once the queue had room")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ps.Drain(ctx); err != nil {
+		t.Fatalf("Drain returned an error: %s", err.Error())
+	}
+	if n := delivered.Load(); n != 3 {
+		t.Fatalf("expected 3 messages to be delivered, got %d", n)
+	}
+}
+
+func TestPublishWaitCanceled(t *testing.T) {
+	ps := New(WithAsync(1))
+	release := make(chan struct{})
+	var delivered atomic.Int32
+	fillQueue(t, ps, release, &delivered)
+	defer ps.Shutdown()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := ps.PublishWait(ctx, "testTopic", 3)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestShutdownConcurrent(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"sync":  nil,
+		"async": {WithAsync(4), WithOverflow(OverflowDropNewest, nil)},
+	} {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			ps := New(opts...)
+			stop := make(chan struct{})
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				topic := "topic" + strconv.Itoa(i)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for {
+						select {
+						case <-stop:
+							return
+						default:
+						}
+						_ = ps.Subscribe(topic, func(args ...any) {
+							// Handlers creating topics while Shutdown runs.
+							_ = ps.Publish(topic+".nested", args...)
+						})
+						_ = ps.Publish(topic, 1)
+						_ = ps.UnsubscribeAll()
+					}
+				}()
+			}
+
+			for i := 0; i < 20; i++ {
+				_ = ps.Shutdown()
+			}
+			close(stop)
+			wg.Wait()
+			_ = ps.Shutdown()
+
+			if topics := ps.Topics(); len(topics) != 0 {
+				t.Fatalf("expected no open topics after the final Shutdown, got %v", topics)
+			}
+			if snapshot := ps.Snapshot(); len(snapshot) != 0 {
+				t.Fatalf("expected no handlers after the final Shutdown, got %v", snapshot)
+			}
+		})
+	}
+}
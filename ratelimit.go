@@ -0,0 +1,107 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrInvalidRateLimit is returned when publishing to a topic whose rate limit, set with
+// WithRateLimit, doesn't allow any message per second or has a burst below 1.
+var ErrInvalidRateLimit = errors.New("pubsub: invalid rate limit")
+
+// RateLimitMode determines what Publish does when a topic's rate limit is exceeded.
+type RateLimitMode int
+
+const (
+	// RateLimitBlock makes Publish wait until the message can be delivered.
+	RateLimitBlock RateLimitMode = iota
+	// RateLimitDrop makes Publish drop the message.
+	RateLimitDrop
+)
+
+// rateLimit is the configuration of a topic's rate limit.
+type rateLimit struct {
+	ratePerSec float64
+	burst      int
+}
+
+// tokenBucket is a token-bucket rate limiter.
+type tokenBucket struct {
+	clock Clock
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a limiter for the rate limit, or ErrInvalidRateLimit if its
+// rate isn't positive or its burst is below 1, which would never hold a token.
+func newTokenBucket(limit rateLimit, clock Clock) (*tokenBucket, error) {
+	if !(limit.ratePerSec > 0) {
+		return nil, fmt.Errorf("%w: %v messages per second", ErrInvalidRateLimit, limit.ratePerSec)
+	}
+	if limit.burst < 1 {
+		return nil, fmt.Errorf("%w: burst of %d", ErrInvalidRateLimit, limit.burst)
+	}
+	return &tokenBucket{
+		clock:  clock,
+		rate:   limit.ratePerSec,
+		burst:  float64(limit.burst),
+		tokens: float64(limit.burst),
+		last:   clock.Now(),
+	}, nil
+}
+
+// refill adds the tokens accumulated since the last call. It must be called with mu held.
+func (b *tokenBucket) refill() {
+	now := b.clock.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+// allow takes a token if one is available and reports whether it did.
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// wait takes a token, blocking until one is available.
+func (b *tokenBucket) wait() {
+	b.mu.Lock()
+	b.refill()
+	// Take the token up front so concurrent waiters queue up behind each other.
+	b.tokens--
+	missing := -b.tokens
+	b.mu.Unlock()
+	if missing > 0 {
+		<-b.clock.After(time.Duration(missing / b.rate * float64(time.Second)))
+	}
+}
+
+// normalizeRateLimits keys the rate limits set with WithRateLimit by the normalized names
+// of their topics, like normalizePartitioners.
+func (p *pubsub) normalizeRateLimits() {
+	if !p.opts.strictTopics || len(p.opts.rateLimits) == 0 {
+		return
+	}
+	rateLimits := make(map[string]rateLimit, len(p.opts.rateLimits))
+	for topic, limit := range p.opts.rateLimits {
+		if name, err := p.topicName(topic); err == nil {
+			rateLimits[name] = limit
+		}
+	}
+	p.opts.rateLimits = rateLimits
+}
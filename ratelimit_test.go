@@ -0,0 +1,96 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimitBlock(t *testing.T) {
+	ps := New(WithRateLimit("limited", 100, 5))
+	calls := 0
+	if err := ps.Subscribe("limited", func(args ...any) { calls++ }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	start := time.Now()
+	for i := 0; i < 15; i++ {
+		if err := ps.Publish("limited", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	elapsed := time.Since(start)
+
+	if calls != 15 {
+		t.Errorf("expected all 15 messages to be delivered, got %d", calls)
+	}
+	// The burst is delivered immediately and the remaining 10 at 100 per second.
+	if elapsed < 90*time.Millisecond {
+		t.Errorf("expected delivery to be throttled to about 100ms, took %s", elapsed)
+	}
+}
+
+func TestRateLimitDrop(t *testing.T) {
+	ps := New(WithRateLimit("limited", 10, 5), WithRateLimitMode(RateLimitDrop))
+	limited, unlimited := 0, 0
+	if err := ps.Subscribe("limited", func(args ...any) { limited++ }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("unlimited", func(args ...any) { unlimited++ }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	for i := 0; i < 20; i++ {
+		if err := ps.Publish("limited", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+		if err := ps.Publish("unlimited", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+
+	// Only the burst fits, give or take a token refilled while publishing.
+	if limited < 5 || limited > 6 {
+		t.Errorf("expected about 5 messages to be delivered on the limited topic, got %d", limited)
+	}
+	if unlimited != 20 {
+		t.Errorf("expected the unlimited topic to be unaffected, got %d", unlimited)
+	}
+}
+
+func TestRateLimitInvalid(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		ps := New(WithRateLimit("limited", rate, 1))
+		if err := ps.Subscribe("limited", func(args ...any) {}); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+		if err := ps.Publish("limited"); !errors.Is(err, ErrInvalidRateLimit) {
+			t.Errorf("expected ErrInvalidRateLimit for a rate of %v, got %v", rate, err)
+		}
+	}
+	for _, burst := range []int{0, -1} {
+		ps := New(WithRateLimit("limited", 10, burst), WithRateLimitMode(RateLimitDrop))
+		if err := ps.Subscribe("limited", func(args ...any) {}); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+		if err := ps.Publish("limited"); !errors.Is(err, ErrInvalidRateLimit) {
+			t.Errorf("expected ErrInvalidRateLimit for a burst of %d, got %v", burst, err)
+		}
+	}
+}
+
+func TestRateLimitStrictTopics(t *testing.T) {
+	ps := New(WithStrictTopics(), WithRateLimit(" limited ", 10, 1), WithRateLimitMode(RateLimitDrop))
+	calls := 0
+	if err := ps.Subscribe("limited", func(args ...any) { calls++ }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	for i := 0; i < 5; i++ {
+		if err := ps.Publish("limited", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the rate limit of the untrimmed name to apply, got %d calls", calls)
+	}
+}
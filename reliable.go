@@ -0,0 +1,140 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNotAcknowledged is published to the dead-letter topic with a message that a
+// reliable subscriber did not acknowledge within the maximum number of attempts.
+var ErrNotAcknowledged = errors.New("pubsub: message not acknowledged")
+
+// SubscribeReliable adds a handler to the topic that must acknowledge each message by calling ack.
+// A message that isn't acknowledged within the visibility timeout is delivered again, until the
+// maximum number of attempts is reached and it is published to the dead-letter topic.
+// See WithRedelivery and WithDeadLetter. Redeliveries happen on their own goroutine. Once the
+// handler is removed from the topic, for example by Unsubscribe or Shutdown, pending
+// redeliveries are abandoned and unacknowledged messages are no longer dead-lettered.
+func (p *pubsub) SubscribeReliable(topic string, handler func(args []any, ack func())) error {
+	if handler == nil {
+		return ErrNilHandler
+	}
+	topic, err := p.topicName(topic)
+	if err != nil {
+		return err
+	}
+	r := &reliable{
+		p:       p,
+		topic:   topic,
+		handler: handler,
+		pending: make(map[*reliableDelivery]bool),
+	}
+	_, err = p.subscribe(topic, &subscription{handler: r.receive, stop: r.stop})
+	return err
+}
+
+// reliable delivers messages to a reliable subscriber until they are acknowledged.
+type reliable struct {
+	p       *pubsub
+	topic   string
+	handler func(args []any, ack func())
+
+	// mu guards the fields below. It is locked before the mu of a reliableDelivery.
+	mu      sync.Mutex
+	stopped bool
+	// pending holds the deliveries that haven't been acknowledged or dead-lettered yet.
+	pending map[*reliableDelivery]bool
+}
+
+func (r *reliable) receive(args ...any) {
+	d := &reliableDelivery{r: r, args: args}
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return
+	}
+	r.pending[d] = true
+	r.mu.Unlock()
+	d.attempt()
+}
+
+// stop abandons the pending deliveries when the handler is removed from the topic.
+func (r *reliable) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+	for d := range r.pending {
+		d.mu.Lock()
+		if d.timer != nil {
+			d.timer.Stop()
+		}
+		d.mu.Unlock()
+	}
+	r.pending = nil
+}
+
+// reliableDelivery tracks the delivery of a single message to a reliable subscriber.
+type reliableDelivery struct {
+	r    *reliable
+	args []any
+
+	mu       sync.Mutex
+	attempts int
+	acked    bool
+	timer    Timer
+}
+
+func (d *reliableDelivery) attempt() {
+	d.r.mu.Lock()
+	if d.r.stopped {
+		d.r.mu.Unlock()
+		return
+	}
+	d.mu.Lock()
+	if d.acked {
+		d.mu.Unlock()
+		d.r.mu.Unlock()
+		return
+	}
+	d.attempts++
+	d.timer = d.r.p.opts.clock.AfterFunc(d.r.p.opts.visibilityTimeout, d.expire)
+	d.mu.Unlock()
+	d.r.mu.Unlock()
+	d.r.handler(d.args, d.ack)
+}
+
+func (d *reliableDelivery) expire() {
+	d.r.mu.Lock()
+	if d.r.stopped {
+		d.r.mu.Unlock()
+		return
+	}
+	d.mu.Lock()
+	acked, attempts := d.acked, d.attempts
+	d.mu.Unlock()
+	if acked {
+		d.r.mu.Unlock()
+		return
+	}
+	if attempts < d.r.p.opts.maxAttempts {
+		d.r.mu.Unlock()
+		d.attempt()
+		return
+	}
+	delete(d.r.pending, d)
+	d.r.mu.Unlock()
+	d.r.p.deadLetter(d.args, fmt.Errorf("%w: topic %q after %d attempts", ErrNotAcknowledged, d.r.topic, attempts))
+}
+
+func (d *reliableDelivery) ack() {
+	d.mu.Lock()
+	d.acked = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.mu.Unlock()
+	d.r.mu.Lock()
+	delete(d.r.pending, d)
+	d.r.mu.Unlock()
+}
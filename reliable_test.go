@@ -0,0 +1,113 @@
+package pubsub
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubscribeReliableRedelivers(t *testing.T) {
+	ps := New(WithRedelivery(10*time.Millisecond, 3))
+
+	var attempts atomic.Int32
+	acked := make(chan struct{})
+	err := ps.SubscribeReliable("jobs", func(args []any, ack func()) {
+		if args[0] != "job" {
+			t.Errorf("expected arg %q, got %v", "job", args[0])
+		}
+		// Let the first delivery time out and acknowledge the second.
+		if attempts.Add(1) == 2 {
+			ack()
+			close(acked)
+		}
+	})
+	if err != nil {
+		t.Fatalf("SubscribeReliable returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("jobs", "job"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	select {
+	case <-acked:
+	case <-time.After(time.Second):
+		t.Fatal("message was not redelivered")
+	}
+
+	// Wait past another visibility timeout to make sure redelivery stopped.
+	time.Sleep(50 * time.Millisecond)
+	if n := attempts.Load(); n != 2 {
+		t.Errorf("expected 2 attempts, got %d", n)
+	}
+}
+
+func TestSubscribeReliableDeadLetters(t *testing.T) {
+	ps := New(WithRedelivery(10*time.Millisecond, 3), WithDeadLetter("dead"))
+
+	var attempts atomic.Int32
+	err := ps.SubscribeReliable("jobs", func(args []any, ack func()) {
+		attempts.Add(1)
+	})
+	if err != nil {
+		t.Fatalf("SubscribeReliable returned an error: %s", err.Error())
+	}
+	dead := make(chan []any, 1)
+	err = ps.Subscribe("dead", func(args ...any) {
+		dead <- args
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("jobs", "job"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	select {
+	case args := <-dead:
+		if len(args) != 2 || args[0] != "job" {
+			t.Fatalf("expected the original arg followed by an error, got %v", args)
+		}
+		if err, ok := args[1].(error); !ok || !errors.Is(err, ErrNotAcknowledged) {
+			t.Errorf("expected ErrNotAcknowledged, got %v", args[1])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message was not dead-lettered")
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+}
+
+func TestSubscribeReliableStopsOnShutdown(t *testing.T) {
+	clock := newManualClock()
+	ps := New(WithClock(clock), WithRedelivery(time.Second, 100), WithDeadLetter("dead"))
+	var dead int
+	if err := ps.Subscribe("dead", func(args ...any) { dead++ }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	var attempts int
+	if err := ps.SubscribeReliable("jobs", func(args []any, ack func()) { attempts++ }); err != nil {
+		t.Fatalf("SubscribeReliable returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("jobs", "job"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	clock.Advance(time.Second)
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts before Shutdown, got %d", attempts)
+	}
+	if err := ps.Shutdown(); err != nil {
+		t.Fatalf("Shutdown returned an error: %s", err.Error())
+	}
+	for i := 0; i < 10; i++ {
+		clock.Advance(time.Second)
+	}
+	if attempts != 2 {
+		t.Errorf("expected no redelivery after Shutdown, got %d attempts", attempts)
+	}
+	if dead != 0 {
+		t.Errorf("expected nothing to be dead-lettered after Shutdown, got %d messages", dead)
+	}
+}
@@ -0,0 +1,163 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ErrRequestTimeout is returned by Request when no handler replies within the timeout.
+var ErrRequestTimeout = errors.New("pubsub: request timed out")
+
+// Requester is the interface that wraps the Respond, Request and RequestHedged methods.
+// Respond sets the handler that answers requests made on the topic.
+// Request sends a request to the responder of the topic and waits for its reply.
+// RequestHedged is like Request but publishes the request again if the first reply is slow.
+type Requester interface {
+	Respond(topic string, handler func(...any) []any) error
+	Request(topic string, timeout time.Duration, args ...any) ([]any, error)
+	RequestHedged(topic string, hedgeAfter time.Duration, timeout time.Duration, args ...any) ([]any, error)
+}
+
+// replyTo is passed to responders ahead of the args of a request so they know where to
+// send the reply, and whether the requester still waits for it.
+type replyTo struct {
+	inbox string
+	// done is set once the request returned, after which it isn't answered anymore.
+	done atomic.Bool
+}
+
+// inboxPrefix starts the names of the reply topics of requests.
+const inboxPrefix = "_inbox."
+
+// requestPrefix starts the names of the topics requests are published to, which keeps
+// them apart from the messages of the topics they are made on.
+const requestPrefix = "_request."
+
+// responderKey is the key of the subscription of a responder, so that a topic has at most one.
+const responderKey = "responder"
+
+// requestID generates correlation IDs for requests.
+var requestID atomic.Uint64
+
+// Respond sets the handler that answers requests made on the topic; its return value is
+// sent back to the requester. Requests are kept apart from the topic's messages, so the
+// handler isn't called for regular publishes and the topic's other handlers never see
+// requests. A topic has a single responder: responding again replaces it.
+func (p *pubsub) Respond(topic string, handler func(...any) []any) error {
+	if handler == nil {
+		return ErrNilHandler
+	}
+	topic, err := p.requestTopic(topic)
+	if err != nil {
+		return err
+	}
+	_, err = p.subscribe(topic, &subscription{key: responderKey, handler: func(args ...any) {
+		if len(args) == 0 {
+			return
+		}
+		r, ok := args[0].(*replyTo)
+		if !ok || r.done.Load() {
+			return
+		}
+		reply := handler(args[1:]...)
+		if !r.done.Load() {
+			// Deliver straight to the inbox: with WithGlobalOrder the responder runs on
+			// the dispatcher, which would otherwise wait for itself.
+			_ = p.deliver(context.Background(), r.inbox, reply, false, false, nil)
+		}
+	}})
+	return err
+}
+
+// requestTopic returns the name of the topic requests made on the topic are published to.
+func (p *pubsub) requestTopic(topic string) (string, error) {
+	topic, err := p.topicName(topic)
+	if err != nil {
+		return "", err
+	}
+	if err := p.known(topic); err != nil {
+		return "", err
+	}
+	return requestPrefix + topic, nil
+}
+
+// Request sends args to the responder of the topic and returns its reply.
+// Each request gets its own reply topic named after a correlation ID, so concurrent
+// requests never receive each other's replies. ErrRequestTimeout is returned if no
+// reply arrives within the timeout.
+func (p *pubsub) Request(topic string, timeout time.Duration, args ...any) ([]any, error) {
+	return p.request(topic, 0, timeout, args)
+}
+
+// RequestHedged is like Request, but if no reply arrives within hedgeAfter the request
+// is published a second time and whichever reply arrives first is returned. The other
+// attempt is abandoned: if the responder hasn't been called with it yet, as when it is
+// still queued on an asynchronous PubSub, it never is. A responder that is already
+// running can't be interrupted, though; it finishes in the background after
+// RequestHedged returned, and its reply is discarded. Hedging so trades up to twice
+// the work for a lower latency.
+func (p *pubsub) RequestHedged(topic string, hedgeAfter time.Duration, timeout time.Duration, args ...any) ([]any, error) {
+	return p.request(topic, hedgeAfter, timeout, args)
+}
+
+// request implements Request and RequestHedged. A hedgeAfter of zero disables hedging.
+func (p *pubsub) request(topic string, hedgeAfter, timeout time.Duration, args []any) ([]any, error) {
+	topic, err := p.requestTopic(topic)
+	if err != nil {
+		return nil, err
+	}
+	inbox := inboxPrefix + strconv.FormatUint(requestID.Add(1), 10)
+	replies := make(chan []any, 1)
+	err = p.Subscribe(inbox, func(reply ...any) {
+		select {
+		case replies <- reply:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer p.removeTopic(inbox)
+
+	r := &replyTo{inbox: inbox}
+	defer r.done.Store(true)
+	msg := make([]any, 0, len(args)+1)
+	msg = append(msg, r)
+	msg = append(msg, args...)
+
+	timer := p.opts.clock.NewTimer(timeout)
+	defer timer.Stop()
+	var hedge <-chan time.Time
+	if hedgeAfter > 0 {
+		hedgeTimer := p.opts.clock.NewTimer(hedgeAfter)
+		defer hedgeTimer.Stop()
+		hedge = hedgeTimer.C()
+	}
+
+	// Publish from another goroutine so a slow handler doesn't defeat the timeout.
+	errc := make(chan error, 2)
+	publish := func() {
+		go func() {
+			errc <- p.Publish(topic, msg...)
+		}()
+	}
+	publish()
+	for {
+		select {
+		case reply := <-replies:
+			return reply, nil
+		case err := <-errc:
+			if err != nil {
+				return nil, err
+			}
+		case <-hedge:
+			hedge = nil
+			publish()
+		case <-timer.C():
+			return nil, ErrRequestTimeout
+		}
+	}
+}
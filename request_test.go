@@ -0,0 +1,264 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequest(t *testing.T) {
+	ps := New()
+
+	err := ps.Respond("double", func(args ...any) []any {
+		return []any{args[0].(int) * 2}
+	})
+	if err != nil {
+		t.Fatalf("Respond returned an error: %s", err.Error())
+	}
+
+	reply, err := ps.Request("double", time.Second, 21)
+	if err != nil {
+		t.Fatalf("Request returned an error: %s", err.Error())
+	}
+	if len(reply) != 1 || reply[0] != 42 {
+		t.Errorf("expected reply [42], got %v", reply)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	ps := New()
+
+	_, err := ps.Request("nobody", 10*time.Millisecond, "ping")
+	if !errors.Is(err, ErrRequestTimeout) {
+		t.Errorf("expected ErrRequestTimeout, got %v", err)
+	}
+
+	// A responder that is too slow also times out.
+	err = ps.Respond("slow", func(args ...any) []any {
+		time.Sleep(100 * time.Millisecond)
+		return []any{"pong"}
+	})
+	if err != nil {
+		t.Fatalf("Respond returned an error: %s", err.Error())
+	}
+	_, err = ps.Request("slow", 10*time.Millisecond, "ping")
+	if !errors.Is(err, ErrRequestTimeout) {
+		t.Errorf("expected ErrRequestTimeout, got %v", err)
+	}
+}
+
+func TestRequestConcurrent(t *testing.T) {
+	ps := New()
+
+	err := ps.Respond("echo", func(args ...any) []any {
+		// Make the first request finish last.
+		if args[0] == "first" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return args
+	})
+	if err != nil {
+		t.Fatalf("Respond returned an error: %s", err.Error())
+	}
+
+	var wg sync.WaitGroup
+	for _, msg := range []string{"first", "second"} {
+		wg.Add(1)
+		go func(msg string) {
+			defer wg.Done()
+			reply, err := ps.Request("echo", time.Second, msg)
+			if err != nil {
+				t.Errorf("Request returned an error: %s", err.Error())
+				return
+			}
+			if len(reply) != 1 || reply[0] != msg {
+				t.Errorf("expected reply [%s], got %v", msg, reply)
+			}
+		}(msg)
+	}
+	wg.Wait()
+}
+
+func TestRequestHedged(t *testing.T) {
+	clock := newManualClock()
+	ps := New(WithClock(clock))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	var calls atomic.Int32
+	err := ps.Respond("lookup", func(args ...any) []any {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+			defer close(finished)
+			return []any{"first"}
+		}
+		return []any{"hedge"}
+	})
+	if err != nil {
+		t.Fatalf("Respond returned an error: %s", err.Error())
+	}
+
+	type result struct {
+		reply []any
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		reply, err := ps.RequestHedged("lookup", 20*time.Millisecond, time.Second, "key")
+		done <- result{reply, err}
+	}()
+	<-started
+	clock.Advance(20 * time.Millisecond)
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("RequestHedged returned an error: %s", res.err.Error())
+	}
+	if len(res.reply) != 1 || res.reply[0] != "hedge" {
+		t.Errorf("expected the hedge's reply, got %v", res.reply)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected 2 deliveries, got %d", n)
+	}
+	close(release)
+	<-finished
+}
+
+func TestRequestHedgedAbandoned(t *testing.T) {
+	clock := newManualClock()
+	ps := New(WithClock(clock), WithAsync(4))
+	defer ps.Shutdown()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	err := ps.Respond("lookup", func(args ...any) []any {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		return []any{"reply"}
+	})
+	if err != nil {
+		t.Fatalf("Respond returned an error: %s", err.Error())
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := ps.RequestHedged("lookup", 20*time.Millisecond, 50*time.Millisecond, "key")
+		done <- err
+	}()
+	<-started
+	// The hedge is queued behind the first attempt, which doesn't reply in time.
+	clock.Advance(20 * time.Millisecond)
+	clock.Advance(30 * time.Millisecond)
+	if err := <-done; !errors.Is(err, ErrRequestTimeout) {
+		t.Fatalf("expected ErrRequestTimeout, got %v", err)
+	}
+	close(release)
+	if err := ps.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain returned an error: %s", err.Error())
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected the abandoned hedge not to reach the responder, got %d calls", n)
+	}
+}
+
+func TestRequestSeparateFromMessages(t *testing.T) {
+	ps := New()
+
+	var messages [][]any
+	if err := ps.Subscribe("testTopic", func(args ...any) {
+		messages = append(messages, args)
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	var requests [][]any
+	err := ps.Respond("testTopic", func(args ...any) []any {
+		requests = append(requests, args)
+		return []any{"pong"}
+	})
+	if err != nil {
+		t.Fatalf("Respond returned an error: %s", err.Error())
+	}
+
+	if _, err := ps.Request("testTopic", time.Second, "ping"); err != nil {
+		t.Fatalf("Request returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic", "message"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(messages) != 1 || len(messages[0]) != 1 || messages[0][0] != "message" {
+		t.Errorf("expected the handler to only see the message, got %v", messages)
+	}
+	if len(requests) != 1 || len(requests[0]) != 1 || requests[0][0] != "ping" {
+		t.Errorf("expected the responder to only see the request, got %v", requests)
+	}
+
+	want := []string{"testTopic"}
+	if topics := ps.Topics(); !reflect.DeepEqual(topics, want) {
+		t.Errorf("expected topics %v, got %v", want, topics)
+	}
+	if topics := ps.ExportTopics(); !reflect.DeepEqual(topics, want) {
+		t.Errorf("expected exported topics %v, got %v", want, topics)
+	}
+	if snapshot := ps.Snapshot(); !reflect.DeepEqual(snapshot, map[string]int{"testTopic": 1}) {
+		t.Errorf("expected only testTopic in the snapshot, got %v", snapshot)
+	}
+}
+
+func TestRespondReplaces(t *testing.T) {
+	ps := New()
+
+	var calls []string
+	for _, name := range []string{"first", "second"} {
+		name := name
+		err := ps.Respond("testTopic", func(args ...any) []any {
+			calls = append(calls, name)
+			return []any{name}
+		})
+		if err != nil {
+			t.Fatalf("Respond returned an error: %s", err.Error())
+		}
+	}
+
+	reply, err := ps.Request("testTopic", time.Second)
+	if err != nil {
+		t.Fatalf("Request returned an error: %s", err.Error())
+	}
+	if len(reply) != 1 || reply[0] != "second" {
+		t.Errorf("expected the reply of the second responder, got %v", reply)
+	}
+	if len(calls) != 1 {
+		t.Errorf("expected a single responder to be called, got %v", calls)
+	}
+}
+
+func TestRequestGlobalOrder(t *testing.T) {
+	ps := New(WithGlobalOrder(true))
+	defer ps.Shutdown()
+	err := ps.Respond("double", func(args ...any) []any {
+		return []any{args[0].(int) * 2}
+	})
+	if err != nil {
+		t.Fatalf("Respond returned an error: %s", err.Error())
+	}
+
+	withinSecond(t, func() {
+		reply, err := ps.Request("double", time.Second, 21)
+		if err != nil {
+			t.Errorf("Request returned an error: %s", err.Error())
+		} else if len(reply) != 1 || reply[0] != 42 {
+			t.Errorf("expected reply [42], got %v", reply)
+		}
+		// The bus keeps delivering.
+		if err := ps.Publish("other"); err != nil {
+			t.Errorf("Publish returned an error: %s", err.Error())
+		}
+	})
+}
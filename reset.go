@@ -0,0 +1,48 @@
+package pubsub
+
+import "sync"
+
+// Reset shuts the PubSub down and then clears all of its state, so it behaves like one
+// freshly returned by New with the same options: topics, default handlers, aliases,
+// declared topics and drop counts are forgotten, and the workers and timers stopped by
+// Shutdown are restarted. It returns the errors of Shutdown. Reset must not be called
+// concurrently with other methods of the PubSub.
+func (p *pubsub) Reset() error {
+	err := p.Shutdown()
+	for i := range p.shards {
+		sh := &p.shards[i]
+		sh.mu.Lock()
+		sh.topics = make(map[string]*topic)
+		sh.mu.Unlock()
+	}
+	p.drops.Store(0)
+	p.aliasesMu.Lock()
+	p.aliases = nil
+	p.aliasesMu.Unlock()
+	p.declaredMu.Lock()
+	p.declared = nil
+	p.declaredMu.Unlock()
+	p.defaultsMu.Lock()
+	p.defaults = nil
+	p.defaultsMu.Unlock()
+	if p.opts.globalOrder {
+		p.dispatcher = newDispatcher(p, p.opts.globalOrderWait)
+	}
+	if p.opts.topicTTL > 0 {
+		p.sweepStop = make(chan struct{})
+		p.sweepStopOnce = sync.Once{}
+		go p.sweep(p.sweepStop)
+	}
+	p.shutdown.Store(false)
+	return err
+}
+
+// forget deletes t from the topics map unless another topic has replaced it.
+func (p *pubsub) forget(t *topic) {
+	sh := p.shard(t.name)
+	sh.mu.Lock()
+	if sh.topics[t.name] == t {
+		delete(sh.topics, t.name)
+	}
+	sh.mu.Unlock()
+}
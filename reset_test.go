@@ -0,0 +1,76 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReset(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"sync":         nil,
+		"async":        {WithAsync(8)},
+		"global order": {WithGlobalOrder(true)},
+	} {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			ps := New(opts...)
+			if err := ps.Subscribe("testTopic", func(args ...any) {}); err != nil {
+				t.Fatalf("Subscribe returned an error: %s", err.Error())
+			}
+			if err := ps.Shutdown(); err != nil {
+				t.Fatalf("Shutdown returned an error: %s", err.Error())
+			}
+
+			if err := ps.Reset(); err != nil {
+				t.Fatalf("Reset returned an error: %s", err.Error())
+			}
+
+			if topics := ps.Topics(); len(topics) != 0 {
+				t.Fatalf("expected no topics after Reset, got %v", topics)
+			}
+			received := make(chan []any, 1)
+			err := ps.Subscribe("testTopic", func(args ...any) {
+				received <- args
+			})
+			if err != nil {
+				t.Fatalf("Subscribe returned an error: %s", err.Error())
+			}
+			if err := ps.Publish("testTopic", "payload"); err != nil {
+				t.Fatalf("Publish returned an error: %s", err.Error())
+			}
+			select {
+			case args := <-received:
+				if len(args) != 1 || args[0] != "payload" {
+					t.Fatalf("expected [payload], got %v", args)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("message was not delivered after Reset")
+			}
+			if err := ps.Shutdown(); err != nil {
+				t.Fatalf("Shutdown returned an error: %s", err.Error())
+			}
+		})
+	}
+}
+
+func TestResetNamespace(t *testing.T) {
+	ps := New()
+	ns := ps.Namespace("billing")
+	if err := ns.Subscribe("orders", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("shipping", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ns.Reset(); err != nil {
+		t.Fatalf("Reset returned an error: %s", err.Error())
+	}
+
+	if topics := ps.Topics(); len(topics) != 1 || topics[0] != "shipping" {
+		t.Fatalf("expected only the topic outside the namespace to remain, got %v", topics)
+	}
+	if err := ns.Subscribe("orders", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error after Reset: %s", err.Error())
+	}
+}
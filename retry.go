@@ -0,0 +1,130 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRetriesExhausted is published to the dead-letter topic with a message that a handler
+// subscribed with SubscribeWithRetry failed to handle in all its attempts.
+var ErrRetriesExhausted = errors.New("pubsub: handler retries exhausted")
+
+// SubscribeWithRetry adds a handler that can fail to the topic. A message the handler
+// returns an error for, or panics on, is handed to it again after backoff, until it
+// succeeds or has been tried attempts times in total. The message is then published to
+// the dead-letter topic, if there is one and it isn't the topic itself, with an error
+// wrapping ErrRetriesExhausted and the handler's last error, and reported to the error
+// topic. See WithDeadLetter and WithErrorTopic.
+// The first attempt is made on delivery and the retries on their own goroutine, so a
+// failing handler doesn't hold up the others. The handler must therefore be safe to call
+// concurrently with itself, as a retry can be due while it handles another message, and
+// retried messages are handled out of the order they were published in. Drain doesn't
+// wait for pending retries. The returned cancel function removes the handler from the
+// topic and abandons the pending retries; a message whose attempt was already running
+// isn't dead-lettered or reported if that attempt fails.
+func (p *pubsub) SubscribeWithRetry(topic string, attempts int, backoff time.Duration, handler func(...any) error) (cancel func(), err error) {
+	if handler == nil {
+		return nil, ErrNilHandler
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+	topic, err = p.topicName(topic)
+	if err != nil {
+		return nil, err
+	}
+	r := &retrier{
+		p:        p,
+		topic:    topic,
+		attempts: attempts,
+		backoff:  backoff,
+		handler:  handler,
+		pending:  make(map[*retryDelivery]bool),
+	}
+	return p.subscribe(topic, &subscription{handler: r.receive, stop: r.stop})
+}
+
+// retrier calls a handler that can fail until it succeeds with each message.
+type retrier struct {
+	p        *pubsub
+	topic    string
+	attempts int
+	backoff  time.Duration
+	handler  func(...any) error
+
+	mu      sync.Mutex
+	stopped bool
+	// pending holds the deliveries waiting for a retry.
+	pending map[*retryDelivery]bool
+}
+
+// retryDelivery tracks the attempts to handle a single message.
+type retryDelivery struct {
+	args     []any
+	attempts int
+	timer    Timer
+}
+
+func (r *retrier) receive(args ...any) {
+	r.attempt(&retryDelivery{args: args})
+}
+
+func (r *retrier) attempt(d *retryDelivery) {
+	d.attempts++
+	err := r.call(d.args)
+	if err == nil {
+		return
+	}
+	if d.attempts >= r.attempts {
+		r.mu.Lock()
+		stopped := r.stopped
+		r.mu.Unlock()
+		if stopped {
+			return
+		}
+		err = fmt.Errorf("%w: topic %q after %d attempts: %w", ErrRetriesExhausted, r.topic, d.attempts, err)
+		if r.topic != r.p.opts.deadLetterTopic {
+			r.p.deadLetter(d.args, err)
+		}
+		r.p.failed(&HandlerError{Topic: r.topic, Index: -1, Err: err, Args: d.args})
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return
+	}
+	r.pending[d] = true
+	d.timer = r.p.opts.clock.AfterFunc(r.backoff, func() {
+		r.mu.Lock()
+		pending := r.pending[d]
+		delete(r.pending, d)
+		r.mu.Unlock()
+		if pending {
+			r.attempt(d)
+		}
+	})
+}
+
+// call calls the handler, turning a panic into an error.
+func (r *retrier) call(args []any) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("%w on topic %q: %v", ErrHandlerPanic, r.topic, v)
+		}
+	}()
+	return r.handler(args...)
+}
+
+// stop abandons the pending retries when the handler is removed from the topic.
+func (r *retrier) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+	for d := range r.pending {
+		d.timer.Stop()
+	}
+	r.pending = nil
+}
@@ -0,0 +1,237 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSubscribeWithRetry(t *testing.T) {
+	clock := newManualClock()
+	ps := New(WithClock(clock), WithDeadLetter("dead"))
+	var dead int
+	if err := ps.Subscribe("dead", func(args ...any) { dead++ }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	var attempts []any
+	_, err := ps.SubscribeWithRetry("jobs", 3, time.Second, func(args ...any) error {
+		attempts = append(attempts, args[0])
+		if len(attempts) < 3 {
+			return errors.New("flaky")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SubscribeWithRetry returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("jobs", "job"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(attempts) != 1 {
+		t.Fatalf("expected 1 attempt on publish, got %d", len(attempts))
+	}
+	// Retries wait for the backoff.
+	clock.Advance(time.Second / 2)
+	if len(attempts) != 1 {
+		t.Fatalf("expected no retry before the backoff, got %d attempts", len(attempts))
+	}
+	clock.Advance(time.Second / 2)
+	clock.Advance(time.Second)
+	clock.Advance(time.Second)
+	if len(attempts) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(attempts))
+	}
+	for _, arg := range attempts {
+		if arg != "job" {
+			t.Errorf("expected every attempt to receive job, got %v", arg)
+		}
+	}
+	if dead != 0 {
+		t.Errorf("expected nothing to be dead-lettered, got %d messages", dead)
+	}
+}
+
+func TestSubscribeWithRetryExhausted(t *testing.T) {
+	clock := newManualClock()
+	ps := New(WithClock(clock), WithDeadLetter("dead"))
+	var dead [][]any
+	if err := ps.Subscribe("dead", func(args ...any) { dead = append(dead, args) }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	errFailed := errors.New("failed")
+	attempts := 0
+	_, err := ps.SubscribeWithRetry("jobs", 3, time.Second, func(args ...any) error {
+		attempts++
+		if attempts == 2 {
+			panic("boom")
+		}
+		return errFailed
+	})
+	if err != nil {
+		t.Fatalf("SubscribeWithRetry returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("jobs", "job"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	for i := 0; i < 5; i++ {
+		clock.Advance(time.Second)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+	if len(dead) != 1 {
+		t.Fatalf("expected 1 dead-lettered message, got %d", len(dead))
+	}
+	if args := dead[0]; len(args) != 2 || args[0] != "job" {
+		t.Fatalf("expected the original arg followed by an error, got %v", args)
+	} else if err, ok := args[1].(error); !ok || !errors.Is(err, ErrRetriesExhausted) || !errors.Is(err, errFailed) {
+		t.Errorf("expected ErrRetriesExhausted wrapping the handler's error, got %v", args[1])
+	}
+}
+
+func TestSubscribeWithRetryErrorTopic(t *testing.T) {
+	clock := newManualClock()
+	ps := New(WithClock(clock), WithErrorTopic("errors"))
+	var events []Event
+	if _, err := ps.SubscribeEvents("errors", func(event Event) { events = append(events, event) }); err != nil {
+		t.Fatalf("SubscribeEvents returned an error: %s", err.Error())
+	}
+	errFailed := errors.New("failed")
+	_, err := ps.SubscribeWithRetry("jobs", 2, time.Second, func(args ...any) error { return errFailed })
+	if err != nil {
+		t.Fatalf("SubscribeWithRetry returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("jobs", "job"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no event before the retries are exhausted, got %d", len(events))
+	}
+	clock.Advance(time.Second)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event on the error topic, got %d", len(events))
+	}
+	handlerErr, ok := events[0].Payload.(*HandlerError)
+	if !ok {
+		t.Fatalf("expected a *HandlerError payload, got %T", events[0].Payload)
+	}
+	if handlerErr.Topic != "jobs" || !errors.Is(handlerErr, ErrRetriesExhausted) || !errors.Is(handlerErr, errFailed) {
+		t.Errorf("expected jobs to fail with ErrRetriesExhausted wrapping %v, got %v", errFailed, handlerErr)
+	}
+	if len(handlerErr.Args) != 1 || handlerErr.Args[0] != "job" {
+		t.Errorf("expected the message's args, got %v", handlerErr.Args)
+	}
+}
+
+func TestSubscribeWithRetryCancel(t *testing.T) {
+	clock := newManualClock()
+	ps := New(WithClock(clock))
+	attempts := 0
+	cancel, err := ps.SubscribeWithRetry("jobs", 3, time.Second, func(args ...any) error {
+		attempts++
+		return errors.New("failed")
+	})
+	if err != nil {
+		t.Fatalf("SubscribeWithRetry returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("jobs", "job"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	cancel()
+	clock.Advance(time.Second)
+	if attempts != 1 {
+		t.Fatalf("expected no retry after cancel, got %d attempts", attempts)
+	}
+}
+
+func TestSubscribeWithRetryAsync(t *testing.T) {
+	ps := New(WithAsync(4))
+	defer ps.Shutdown()
+
+	retried := make(chan struct{})
+	attempts := 0
+	_, err := ps.SubscribeWithRetry("jobs", 2, 50*time.Millisecond, func(args ...any) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("failed")
+		}
+		close(retried)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SubscribeWithRetry returned an error: %s", err.Error())
+	}
+	other := make(chan struct{})
+	if err := ps.Subscribe("jobs", func(args ...any) { close(other) }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("jobs", "job"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	// The other handler doesn't wait for the retry.
+	select {
+	case <-other:
+	case <-retried:
+		t.Fatal("the other handler waited for the retry")
+	case <-time.After(time.Second):
+		t.Fatal("the other handler was not called")
+	}
+	select {
+	case <-retried:
+	case <-time.After(time.Second):
+		t.Fatal("the message was not retried")
+	}
+}
+
+func TestSubscribeWithRetryCancelDuringAttempt(t *testing.T) {
+	ps := New(WithDeadLetter("dead"))
+	var dead int
+	if err := ps.Subscribe("dead", func(args ...any) { dead++ }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	var cancel func()
+	cancel, err := ps.SubscribeWithRetry("jobs", 1, time.Second, func(args ...any) error {
+		cancel()
+		return errors.New("failed")
+	})
+	if err != nil {
+		t.Fatalf("SubscribeWithRetry returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("jobs", "job"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if dead != 0 {
+		t.Errorf("expected nothing to be dead-lettered after cancel, got %d messages", dead)
+	}
+}
+
+func TestSubscribeWithRetryDeadLetterTopic(t *testing.T) {
+	clock := newManualClock()
+	ps := New(WithClock(clock), WithDeadLetter("dead"))
+	attempts := 0
+	_, err := ps.SubscribeWithRetry("dead", 2, time.Second, func(args ...any) error {
+		attempts++
+		return errors.New("failed")
+	})
+	if err != nil {
+		t.Fatalf("SubscribeWithRetry returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("dead", "message"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	for i := 0; i < 5; i++ {
+		clock.Advance(time.Second)
+	}
+	if attempts != 2 {
+		t.Errorf("expected the exhausted message not to be dead-lettered to the handler again, got %d attempts", attempts)
+	}
+}
@@ -0,0 +1,50 @@
+package pubsub
+
+import "time"
+
+// PublishAfter publishes args to the topic once delay has elapsed.
+// The returned cancel function prevents the publish if it hasn't happened yet.
+// Publishes that are still pending when the PubSub is shut down are cancelled, and
+// scheduling one afterwards returns ErrShutdown.
+func (p *pubsub) PublishAfter(topic string, delay time.Duration, args ...any) (cancel func(), err error) {
+	p.timersMu.Lock()
+	defer p.timersMu.Unlock()
+	if p.shutdown.Load() {
+		return nil, ErrShutdown
+	}
+	p.nextTimer++
+	id := p.nextTimer
+	if p.timers == nil {
+		p.timers = make(map[uint64]Timer)
+	}
+	p.timers[id] = p.opts.clock.AfterFunc(delay, func() {
+		if p.takeTimer(id) != nil {
+			_ = p.Publish(topic, args...)
+		}
+	})
+	return func() {
+		if timer := p.takeTimer(id); timer != nil {
+			timer.Stop()
+		}
+	}, nil
+}
+
+// takeTimer removes the timer with the given id from the pending timers and returns it.
+// It returns nil if the timer already fired or was cancelled.
+func (p *pubsub) takeTimer(id uint64) Timer {
+	p.timersMu.Lock()
+	defer p.timersMu.Unlock()
+	timer := p.timers[id]
+	delete(p.timers, id)
+	return timer
+}
+
+// stopTimers cancels all pending publishes.
+func (p *pubsub) stopTimers() {
+	p.timersMu.Lock()
+	defer p.timersMu.Unlock()
+	for _, timer := range p.timers {
+		timer.Stop()
+	}
+	p.timers = nil
+}
@@ -0,0 +1,82 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPublishAfter(t *testing.T) {
+	ps := New()
+	received := make(chan []any, 1)
+	if err := ps.Subscribe("testTopic", func(args ...any) { received <- args }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	start := time.Now()
+	if _, err := ps.PublishAfter("testTopic", 20*time.Millisecond, "later"); err != nil {
+		t.Fatalf("PublishAfter returned an error: %s", err.Error())
+	}
+	select {
+	case args := <-received:
+		if len(args) != 1 || args[0] != "later" {
+			t.Errorf("expected [later], got %v", args)
+		}
+		if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+			t.Errorf("message was published after %s, before the delay", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("scheduled message was not published")
+	}
+}
+
+func TestPublishAfterCancel(t *testing.T) {
+	ps := New()
+	received := make(chan []any, 1)
+	if err := ps.Subscribe("testTopic", func(args ...any) { received <- args }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	cancel, err := ps.PublishAfter("testTopic", 10*time.Millisecond, "later")
+	if err != nil {
+		t.Fatalf("PublishAfter returned an error: %s", err.Error())
+	}
+	cancel()
+	select {
+	case <-received:
+		t.Error("cancelled message was published")
+	case <-time.After(50 * time.Millisecond):
+	}
+	// Cancelling again is a no-op.
+	cancel()
+}
+
+func TestPublishAfterShutdown(t *testing.T) {
+	ps := New()
+	received := make(chan []any, 1)
+	if err := ps.Subscribe("other", func(args ...any) { received <- args }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if _, err := ps.PublishAfter("testTopic", 10*time.Millisecond, "later"); err != nil {
+		t.Fatalf("PublishAfter returned an error: %s", err.Error())
+	}
+	if err := ps.Shutdown(); err != nil {
+		t.Fatalf("Shutdown returned an error: %s", err.Error())
+	}
+	if _, err := ps.PublishAfter("testTopic", 0, "after"); !errors.Is(err, ErrShutdown) {
+		t.Fatalf("expected ErrShutdown scheduling after Shutdown, got %v", err)
+	}
+	// Reset so a publish that slipped through would reach a subscriber of the topic.
+	if err := ps.Reset(); err != nil {
+		t.Fatalf("Reset returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("testTopic", func(args ...any) { received <- args }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	select {
+	case <-received:
+		t.Error("message scheduled before Shutdown was published")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
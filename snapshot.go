@@ -0,0 +1,113 @@
+package pubsub
+
+import "sort"
+
+// Snapshot returns the number of handlers of each open topic, leaving out the topics
+// the PubSub publishes to itself, as Topics does.
+// All topics are locked while it is taken, so it reflects a single instant,
+// and the returned map isn't affected by later changes.
+func (p *pubsub) Snapshot() map[string]int {
+	topics, unlock := p.lockTopics()
+	defer unlock()
+	snapshot := make(map[string]int, len(topics))
+	for _, t := range topics {
+		if !t.closed && !p.internal(t.name) {
+			snapshot[t.name] = t.subs.len()
+		}
+	}
+	return snapshot
+}
+
+// Range calls fn with the name and number of handlers of each open topic, in name order,
+// until fn returns false. The topics are taken from a Snapshot, so they reflect a single
+// instant however the PubSub changes during the iteration, and fn may use the PubSub.
+func (p *pubsub) Range(fn func(topic string, subscriberCount int) bool) {
+	rangeSnapshot(p.Snapshot(), fn)
+}
+
+// rangeSnapshot calls fn for each topic of snapshot in name order until fn returns false.
+func rangeSnapshot(snapshot map[string]int, fn func(topic string, subscriberCount int) bool) {
+	names := make([]string, 0, len(snapshot))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !fn(name, snapshot[name]) {
+			return
+		}
+	}
+}
+
+// Clone returns an independent PubSub with the same options, open topics, handlers and aliases.
+// The handlers are shared: the clone calls the same func values as p. Pending delayed
+// publishes and paused messages are not copied. Like p, the clone delivers in global order
+// and expires idle topics if its options say so.
+func (p *pubsub) Clone() PubSub {
+	_, unlock := p.lockTopics()
+	defer unlock()
+	clone := &pubsub{
+		opts:   p.opts,
+		shards: make([]topicShard, len(p.shards)),
+	}
+	for i := range p.shards {
+		clone.shards[i].topics = make(map[string]*topic, len(p.shards[i].topics))
+		for name, t := range p.shards[i].topics {
+			if t.closed {
+				continue
+			}
+			ct := newTopic(clone, name)
+			for _, sub := range t.subs.all() {
+				ct.subs.add(sub)
+			}
+			ct.paused = t.paused
+			clone.shards[i].topics[name] = ct
+			clone.openTopics.Add(1)
+		}
+	}
+	p.aliasesMu.RLock()
+	defer p.aliasesMu.RUnlock()
+	for from, to := range p.aliases {
+		if clone.aliases == nil {
+			clone.aliases = make(map[string]string, len(p.aliases))
+		}
+		clone.aliases[from] = to
+	}
+	p.declaredMu.RLock()
+	defer p.declaredMu.RUnlock()
+	for topic := range p.declared {
+		if clone.declared == nil {
+			clone.declared = make(map[string]bool, len(p.declared))
+		}
+		clone.declared[topic] = true
+	}
+	p.defaultsMu.RLock()
+	clone.defaults = p.defaults
+	p.defaultsMu.RUnlock()
+	clone.start()
+	return clone
+}
+
+// lockTopics locks all shards and all topics and returns the topics, sorted by name, and
+// a function that unlocks them. The topics are locked in name order, like lockForAdd
+// does, so concurrent calls can't deadlock with each other or with SubscribeMany.
+func (p *pubsub) lockTopics() (topics []*topic, unlock func()) {
+	for i := range p.shards {
+		p.shards[i].mu.RLock()
+		for _, t := range p.shards[i].topics {
+			topics = append(topics, t)
+		}
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].name < topics[j].name })
+	for _, t := range topics {
+		t.mu.Lock()
+	}
+	return topics, func() {
+		for _, t := range topics {
+			t.mu.Unlock()
+		}
+		for i := range p.shards {
+			p.shards[i].mu.RUnlock()
+		}
+	}
+}
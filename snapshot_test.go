@@ -0,0 +1,164 @@
+package pubsub
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	ps := New()
+	for _, topic := range []string{"a", "a", "b"} {
+		if err := ps.Subscribe(topic, func(args ...any) {}); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+	if err := ps.Subscribe("closed", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.CloseTopic("closed"); err != nil {
+		t.Fatalf("CloseTopic returned an error: %s", err.Error())
+	}
+
+	snapshot := ps.Snapshot()
+	want := map[string]int{"a": 2, "b": 1}
+	if !reflect.DeepEqual(snapshot, want) {
+		t.Errorf("expected snapshot %v, got %v", want, snapshot)
+	}
+
+	if err := ps.Subscribe("c", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(snapshot, want) {
+		t.Errorf("snapshot changed after a later Subscribe: %v", snapshot)
+	}
+}
+
+func TestClone(t *testing.T) {
+	ps := New()
+	calls := 0
+	if err := ps.Subscribe("a", func(args ...any) { calls++ }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	clone := ps.Clone()
+	if err := clone.Publish("a"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if calls != 1 {
+		t.Errorf("expected the shared handler to be called by the clone, got %d calls", calls)
+	}
+
+	// Changes to the clone don't affect the original and vice versa.
+	if err := clone.Subscribe("a", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.CloseTopic("a"); err != nil {
+		t.Fatalf("CloseTopic returned an error: %s", err.Error())
+	}
+	if n := ps.Snapshot()["a"]; n != 0 {
+		t.Errorf("expected the original topic to be closed, got %d handlers", n)
+	}
+	if n := clone.Snapshot()["a"]; n != 2 {
+		t.Errorf("expected the clone to have 2 handlers, got %d", n)
+	}
+}
+
+func TestRange(t *testing.T) {
+	ps := New()
+	for _, topic := range []string{"a", "b", "c"} {
+		if err := ps.Subscribe(topic, func(args ...any) {}); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+	if err := ps.Subscribe("b", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.CloseTopic("c"); err != nil {
+		t.Fatalf("CloseTopic returned an error: %s", err.Error())
+	}
+
+	counts := make(map[string]int)
+	ps.Range(func(topic string, subscriberCount int) bool {
+		counts[topic] = subscriberCount
+		return true
+	})
+
+	want := map[string]int{"a": 1, "b": 2}
+	if !reflect.DeepEqual(counts, want) {
+		t.Fatalf("expected %v, got %v", want, counts)
+	}
+}
+
+func TestRangeStopsEarly(t *testing.T) {
+	ps := New()
+	for _, topic := range []string{"a", "b", "c"} {
+		if err := ps.Subscribe(topic, func(args ...any) {}); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	var visited []string
+	ps.Range(func(topic string, subscriberCount int) bool {
+		visited = append(visited, topic)
+		return len(visited) < 2
+	})
+
+	if !reflect.DeepEqual(visited, []string{"a", "b"}) {
+		t.Fatalf("expected Range to stop after b, got %v", visited)
+	}
+}
+
+func TestSnapshotConcurrent(t *testing.T) {
+	ps := New(WithShards(1))
+	topics := make([]string, 50)
+	for i := range topics {
+		topics[i] = "topic" + strconv.Itoa(i)
+		if err := ps.Subscribe(topics[i], func(args ...any) {}); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	withinSecond(t, func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 200; j++ {
+					if n := len(ps.Snapshot()); n != len(topics) {
+						t.Errorf("expected %d topics, got %d", len(topics), n)
+						return
+					}
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					cancel, err := ps.SubscribeMany(topics, func(args ...any) {})
+					if err != nil {
+						t.Errorf("SubscribeMany returned an error: %s", err.Error())
+						return
+					}
+					cancel()
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestCloneStartsGoroutines(t *testing.T) {
+	ps := New(WithGlobalOrder(false), WithTopicTTL(time.Hour))
+	defer ps.Shutdown()
+	clone := ps.Clone().(*pubsub)
+	defer clone.Shutdown()
+	if clone.dispatcher == nil || clone.dispatcher == ps.(*pubsub).dispatcher {
+		t.Error("expected the clone to have its own dispatcher")
+	}
+	if clone.sweepStop == nil {
+		t.Error("expected the clone to expire idle topics")
+	}
+}
@@ -0,0 +1,85 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// HandlerTiming describes how long the handler at Index of a topic took to run.
+// Index is the handler's position among the topic's handlers when it was called.
+type HandlerTiming struct {
+	Index int
+	Count int
+	Min   time.Duration
+	Max   time.Duration
+	Avg   time.Duration
+}
+
+// handlerTimings accumulates the timings of a topic's handlers.
+type handlerTimings struct {
+	mu      sync.Mutex
+	timings []HandlerTiming
+	totals  []time.Duration
+}
+
+func (h *handlerTimings) record(index int, elapsed time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for len(h.timings) <= index {
+		h.timings = append(h.timings, HandlerTiming{Index: len(h.timings)})
+		h.totals = append(h.totals, 0)
+	}
+	timing := &h.timings[index]
+	if timing.Count == 0 || elapsed < timing.Min {
+		timing.Min = elapsed
+	}
+	if elapsed > timing.Max {
+		timing.Max = elapsed
+	}
+	timing.Count++
+	h.totals[index] += elapsed
+	timing.Avg = h.totals[index] / time.Duration(timing.Count)
+}
+
+func (h *handlerTimings) get() []HandlerTiming {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]HandlerTiming(nil), h.timings...)
+}
+
+// countDrops records that n messages published to the topic were discarded.
+func (t *topic) countDrops(n int) {
+	t.drops.Add(uint64(n))
+	t.p.drops.Add(uint64(n))
+	for i := 0; i < n; i++ {
+		t.p.count(MetricDrops, t.name)
+	}
+}
+
+// DroppedCount returns the number of messages published to the topic that were discarded
+// instead of delivered: by the overflow policy, by a rate limit in RateLimitDrop mode,
+// while the topic was paused without a pause buffer or with a full one, or because they were still queued
+// when the topic was closed. It returns 0 for unknown topics.
+func (p *pubsub) DroppedCount(topic string) uint64 {
+	t, ok := p.lookupTopic(topic)
+	if !ok {
+		return 0
+	}
+	return t.drops.Load()
+}
+
+// TotalDropped returns the number of messages of all topics that were discarded, as
+// counted by DroppedCount, including those of topics that have since been removed.
+func (p *pubsub) TotalDropped() uint64 {
+	return p.drops.Load()
+}
+
+// HandlerStats returns how long each handler of the topic took to run.
+// It returns nil unless the PubSub was created with WithHandlerTiming.
+func (p *pubsub) HandlerStats(topic string) []HandlerTiming {
+	t, ok := p.lookupTopic(topic)
+	if !ok || t.timings == nil {
+		return nil
+	}
+	return t.timings.get()
+}
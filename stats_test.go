@@ -0,0 +1,118 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHandlerStats(t *testing.T) {
+	ps := New(WithHandlerTiming())
+	if err := ps.Subscribe("testTopic", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("testTopic", func(args ...any) { time.Sleep(10 * time.Millisecond) }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := ps.Publish("testTopic", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+
+	stats := ps.HandlerStats("testTopic")
+	if len(stats) != 2 {
+		t.Fatalf("expected stats for 2 handlers, got %d", len(stats))
+	}
+	for i, timing := range stats {
+		if timing.Index != i || timing.Count != 3 {
+			t.Errorf("expected handler %d to be called 3 times, got %+v", i, timing)
+		}
+		if timing.Min > timing.Avg || timing.Avg > timing.Max {
+			t.Errorf("expected min <= avg <= max, got %+v", timing)
+		}
+	}
+	if slow := stats[1]; slow.Min < 10*time.Millisecond || slow.Max > time.Second {
+		t.Errorf("expected the sleeping handler to take about 10ms, got %+v", slow)
+	}
+	if fast := stats[0]; fast.Max >= 10*time.Millisecond {
+		t.Errorf("expected the empty handler to be fast, got %+v", fast)
+	}
+}
+
+func TestHandlerStatsDisabled(t *testing.T) {
+	ps := New()
+	if err := ps.Subscribe("testTopic", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if stats := ps.HandlerStats("testTopic"); stats != nil {
+		t.Errorf("expected no stats without WithHandlerTiming, got %v", stats)
+	}
+}
+
+func TestDroppedCount(t *testing.T) {
+	ps := New(WithAsync(1), WithOverflow(OverflowDropNewest, nil))
+	defer ps.Shutdown()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	if err := ps.Subscribe("full", func(args ...any) {
+		if args[0] == 1 {
+			close(started)
+			<-release
+		}
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	// The first message occupies the handler and the second fills the queue.
+	if err := ps.Publish("full", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	<-started
+	for i := 2; i <= 5; i++ {
+		if err := ps.Publish("full", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if n := ps.DroppedCount("full"); n != 3 {
+		t.Errorf("expected 3 dropped messages, got %d", n)
+	}
+	if n := ps.DroppedCount("unknown"); n != 0 {
+		t.Errorf("expected no dropped messages for an unknown topic, got %d", n)
+	}
+}
+
+func TestTotalDropped(t *testing.T) {
+	ps := New(WithRateLimit("limited", 1, 1), WithRateLimitMode(RateLimitDrop))
+	if err := ps.Subscribe("limited", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	// The burst of one lets the first message through.
+	for i := 0; i < 3; i++ {
+		if err := ps.Publish("limited", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+	if n := ps.DroppedCount("limited"); n != 2 {
+		t.Errorf("expected 2 dropped messages, got %d", n)
+	}
+
+	// Messages published to a paused topic without a pause buffer are dropped too.
+	if err := ps.Pause("paused"); err != nil {
+		t.Fatalf("Pause returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("paused", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if n := ps.DroppedCount("paused"); n != 1 {
+		t.Errorf("expected 1 dropped message, got %d", n)
+	}
+	if n := ps.TotalDropped(); n != 3 {
+		t.Errorf("expected 3 dropped messages in total, got %d", n)
+	}
+}
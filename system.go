@@ -0,0 +1,49 @@
+package pubsub
+
+import "strconv"
+
+// SystemTopic is the topic a PubSub created with WithSystemTopic publishes its lifecycle
+// events to. Each message is an Event whose Payload is a SystemEvent.
+const SystemTopic = "$sys/events"
+
+// SystemEventKind is the kind of change a SystemEvent describes.
+type SystemEventKind int
+
+const (
+	TopicCreated SystemEventKind = iota
+	TopicClosed
+	SubscriberAdded
+	SubscriberRemoved
+)
+
+var systemEventKindStrings = [...]string{
+	TopicCreated:      "TopicCreated",
+	TopicClosed:       "TopicClosed",
+	SubscriberAdded:   "SubscriberAdded",
+	SubscriberRemoved: "SubscriberRemoved",
+}
+
+// String returns the name of the kind, e.g. "TopicCreated".
+func (k SystemEventKind) String() string {
+	if k < 0 || int(k) >= len(systemEventKindStrings) {
+		return "SystemEventKind(" + strconv.Itoa(int(k)) + ")"
+	}
+	return systemEventKindStrings[k]
+}
+
+// SystemEvent describes a change of a topic of the PubSub.
+type SystemEvent struct {
+	Kind  SystemEventKind
+	Topic string
+	// Handlers is the number of handlers the topic has after the change.
+	Handlers int
+}
+
+// systemEvent publishes a SystemEvent to SystemTopic, if enabled. Changes of SystemTopic
+// itself are not published, so system events never cause further system events.
+func (p *pubsub) systemEvent(kind SystemEventKind, topic string, handlers int) {
+	if !p.opts.systemTopic || topic == SystemTopic {
+		return
+	}
+	_ = p.Publish(SystemTopic, p.newEvent(SystemTopic, SystemEvent{Kind: kind, Topic: topic, Handlers: handlers}))
+}
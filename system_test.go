@@ -0,0 +1,121 @@
+package pubsub
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSystemTopic(t *testing.T) {
+	ps := New(WithSystemTopic())
+	var events []SystemEvent
+	cancel, err := ps.SubscribeEvents(SystemTopic, func(event Event) {
+		if event.Topic != SystemTopic || event.ID == "" {
+			t.Errorf("expected an event on %s with an ID, got %+v", SystemTopic, event)
+		}
+		events = append(events, event.Payload.(SystemEvent))
+	})
+	if err != nil {
+		t.Fatalf("SubscribeEvents returned an error: %s", err.Error())
+	}
+	defer cancel()
+
+	id, err := ps.SubscribeWithID("orders", func(args ...any) {})
+	if err != nil {
+		t.Fatalf("SubscribeWithID returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("orders", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.UnsubscribeByID("orders", id); err != nil {
+		t.Fatalf("UnsubscribeByID returned an error: %s", err.Error())
+	}
+	if err := ps.CloseTopic("orders"); err != nil {
+		t.Fatalf("CloseTopic returned an error: %s", err.Error())
+	}
+
+	// Subscribing to the system topic itself is not reported.
+	want := []SystemEvent{
+		{Kind: TopicCreated, Topic: "orders"},
+		{Kind: SubscriberAdded, Topic: "orders", Handlers: 1},
+		{Kind: SubscriberAdded, Topic: "orders", Handlers: 2},
+		{Kind: SubscriberRemoved, Topic: "orders", Handlers: 1},
+		{Kind: TopicClosed, Topic: "orders"},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("expected events %+v, got %+v", want, events)
+	}
+}
+
+func TestSystemTopicSubscribeOnce(t *testing.T) {
+	ps := New(WithSystemTopic())
+	var events []SystemEvent
+	if _, err := ps.SubscribeEvents(SystemTopic, func(event Event) {
+		events = append(events, event.Payload.(SystemEvent))
+	}); err != nil {
+		t.Fatalf("SubscribeEvents returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("orders", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	var calls []string
+	if _, err := ps.SubscribeOnce("orders", func(args ...any) {}); err != nil {
+		t.Fatalf("SubscribeOnce returned an error: %s", err.Error())
+	}
+	// The stop hook of a once-subscription runs after its handler.
+	if _, err := ps.(*pubsub).subscribe("orders", &subscription{
+		handler: func(args ...any) { calls = append(calls, "handler") },
+		once:    true,
+		stop:    func() { calls = append(calls, "stop") },
+	}); err != nil {
+		t.Fatalf("subscribe returned an error: %s", err.Error())
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := ps.Publish("orders", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+	}
+
+	want := []SystemEvent{
+		{Kind: TopicCreated, Topic: "orders"},
+		{Kind: SubscriberAdded, Topic: "orders", Handlers: 1},
+		{Kind: SubscriberAdded, Topic: "orders", Handlers: 2},
+		{Kind: SubscriberAdded, Topic: "orders", Handlers: 3},
+		{Kind: SubscriberRemoved, Topic: "orders", Handlers: 1},
+		{Kind: SubscriberRemoved, Topic: "orders", Handlers: 1},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("expected events %+v, got %+v", want, events)
+	}
+	if want := []string{"handler", "stop"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected %v, got %v", want, calls)
+	}
+}
+
+func TestSystemTopicDisabled(t *testing.T) {
+	ps := New()
+	called := false
+	cancel, err := ps.SubscribeEvents(SystemTopic, func(event Event) {
+		called = true
+	})
+	if err != nil {
+		t.Fatalf("SubscribeEvents returned an error: %s", err.Error())
+	}
+	defer cancel()
+
+	if err := ps.Subscribe("orders", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if called {
+		t.Error("expected no system events without WithSystemTopic")
+	}
+}
+
+func TestSystemEventKindString(t *testing.T) {
+	if s := SubscriberAdded.String(); s != "SubscriberAdded" {
+		t.Errorf("expected SubscriberAdded, got %s", s)
+	}
+	if s := SystemEventKind(42).String(); s != "SystemEventKind(42)" {
+		t.Errorf("expected SystemEventKind(42), got %s", s)
+	}
+}
@@ -0,0 +1,38 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidTopic is returned for a topic name that is empty or contains control
+// characters, if the PubSub was created with WithStrictTopics.
+var ErrInvalidTopic = errors.New("pubsub: invalid topic")
+
+// lookupTopic returns the topic of the name normalized by topicName, if it exists.
+// A name that isn't valid has no topic.
+func (p *pubsub) lookupTopic(name string) (*topic, bool) {
+	name, err := p.topicName(name)
+	if err != nil {
+		return nil, false
+	}
+	return p.getTopic(name)
+}
+
+// topicName returns the normalized name of the topic, or an error if it isn't valid.
+// Names are only checked and normalized with WithStrictTopics.
+func (p *pubsub) topicName(name string) (string, error) {
+	if !p.opts.strictTopics {
+		return name, nil
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: empty name", ErrInvalidTopic)
+	}
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("%w: %q contains control characters", ErrInvalidTopic, name)
+	}
+	return name, nil
+}
@@ -0,0 +1,115 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStrictTopics(t *testing.T) {
+	ps := New(WithStrictTopics())
+	handler := func(args ...any) {}
+
+	for _, topic := range []string{"", "  ", "test\nTopic", "test\x00Topic", "\x7f"} {
+		if err := ps.Subscribe(topic, handler); !errors.Is(err, ErrInvalidTopic) {
+			t.Errorf("Subscribe(%q): expected ErrInvalidTopic, got %v", topic, err)
+		}
+		if err := ps.Publish(topic, 1); !errors.Is(err, ErrInvalidTopic) {
+			t.Errorf("Publish(%q): expected ErrInvalidTopic, got %v", topic, err)
+		}
+		if err := ps.CloseTopic(topic); !errors.Is(err, ErrInvalidTopic) {
+			t.Errorf("CloseTopic(%q): expected ErrInvalidTopic, got %v", topic, err)
+		}
+		if err := ps.Resume(topic); !errors.Is(err, ErrInvalidTopic) {
+			t.Errorf("Resume(%q): expected ErrInvalidTopic, got %v", topic, err)
+		}
+		if err := ps.Unsubscribe(topic); !errors.Is(err, ErrInvalidTopic) {
+			t.Errorf("Unsubscribe(%q): expected ErrInvalidTopic, got %v", topic, err)
+		}
+	}
+	if topics := ps.Topics(); len(topics) != 0 {
+		t.Errorf("expected no topics to be created, got %v", topics)
+	}
+}
+
+func TestStrictTopicsTrim(t *testing.T) {
+	ps := New(WithStrictTopics())
+	var received []any
+	if err := ps.Subscribe(" testTopic\t", func(args ...any) {
+		received = append(received, args[0])
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if len(received) != 1 {
+		t.Errorf("expected the trimmed topic name to match, got %v", received)
+	}
+}
+
+func TestStrictTopicsTrimAllMethods(t *testing.T) {
+	ps := New(WithStrictTopics(), WithHistory(2))
+	received := 0
+	if err := ps.Subscribe("testTopic", func(args ...any) { received++ }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	const name = " testTopic\t"
+	if !ps.HasTopic(name) || ps.IsClosed(name) {
+		t.Fatal("expected HasTopic and IsClosed to trim the topic name")
+	}
+	if err := ps.Pause(name); err != nil {
+		t.Fatalf("Pause returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic", 1); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if received != 0 {
+		t.Fatal("expected Pause to trim the topic name")
+	}
+	if n := ps.DroppedCount(name); n != 1 {
+		t.Errorf("expected DroppedCount to trim the topic name and count 1 drop, got %d", n)
+	}
+	if err := ps.Resume(name); err != nil {
+		t.Fatalf("Resume returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic", 2); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if received != 1 {
+		t.Fatalf("expected Resume to trim the topic name, got %d calls", received)
+	}
+	if history := ps.History(name, 2); len(history) != 2 {
+		t.Errorf("expected History to trim the topic name, got %v", history)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ps.WaitForSubscribers(ctx, name, 1); err != nil {
+		t.Errorf("expected WaitForSubscribers to trim the topic name, got %v", err)
+	}
+	if err := ps.Unsubscribe(name); err != nil {
+		t.Fatalf("Unsubscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic", 3); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if received != 1 {
+		t.Error("expected Unsubscribe to trim the topic name")
+	}
+}
+
+func TestLaxTopics(t *testing.T) {
+	ps := New()
+	called := false
+	if err := ps.Subscribe("", func(args ...any) {
+		called = true
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Publish(""); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if !called {
+		t.Error("expected the empty topic name to be accepted by default")
+	}
+}
@@ -0,0 +1,46 @@
+package pubsub
+
+import "time"
+
+// touch records that the topic was just used, if idle topics expire.
+func (t *topic) touch() {
+	if t.p.opts.topicTTL > 0 {
+		t.lastUsed.Store(t.p.opts.clock.Now().UnixNano())
+	}
+}
+
+// sweep periodically removes idle topics until stop is closed. See WithTopicTTL.
+func (p *pubsub) sweep(stop <-chan struct{}) {
+	for {
+		select {
+		case now := <-p.opts.clock.After(p.opts.topicTTL / 2):
+			p.removeIdle(now)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// removeIdle removes the topics that have no handlers, aren't paused and haven't
+// been used since the topic TTL before now.
+func (p *pubsub) removeIdle(now time.Time) {
+	cutoff := now.Add(-p.opts.topicTTL).UnixNano()
+	for i := range p.shards {
+		sh := &p.shards[i]
+		var removed []*topic
+		sh.mu.Lock()
+		for name, t := range sh.topics {
+			t.mu.Lock()
+			idle := t.subs.len() == 0 && !t.paused && t.lastUsed.Load() <= cutoff
+			t.mu.Unlock()
+			if idle {
+				delete(sh.topics, name)
+				removed = append(removed, t)
+			}
+		}
+		sh.mu.Unlock()
+		for _, t := range removed {
+			_ = t.close()
+		}
+	}
+}
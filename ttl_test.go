@@ -0,0 +1,58 @@
+package pubsub
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTopicTTL(t *testing.T) {
+	ps := New(WithTopicTTL(20 * time.Millisecond))
+	defer ps.Shutdown()
+
+	cancel, err := ps.SubscribeFiltered("idle", nil, func(args ...any) {})
+	if err != nil {
+		t.Fatalf("SubscribeFiltered returned an error: %s", err.Error())
+	}
+	if err := ps.Subscribe("busy", func(args ...any) {}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	cancel()
+
+	// Keep publishing to another handler-less topic so it stays active.
+	if err := ps.Pause("active"); err != nil {
+		t.Fatalf("Pause returned an error: %s", err.Error())
+	}
+	if err := ps.Resume("active"); err != nil {
+		t.Fatalf("Resume returned an error: %s", err.Error())
+	}
+	for i := 0; i < 10; i++ {
+		if err := ps.Publish("active", i); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	want := []string{"active", "busy"}
+	if topics := ps.Topics(); !reflect.DeepEqual(topics, want) {
+		t.Errorf("expected topics %v, got %v", want, topics)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	want = []string{"busy"}
+	if topics := ps.Topics(); !reflect.DeepEqual(topics, want) {
+		t.Errorf("expected topics %v after the TTL, got %v", want, topics)
+	}
+
+	// An expired topic can be subscribed to again.
+	called := false
+	if err := ps.Subscribe("idle", func(args ...any) { called = true }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("idle"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if !called {
+		t.Error("Handler of a re-created topic was not called")
+	}
+}
@@ -0,0 +1,26 @@
+package pubsub
+
+import "context"
+
+// SubscribeUntil adds a handler to the topic and removes it again when ctx is done.
+// If the handler is removed before that, for example by Unsubscribe or because the topic
+// is closed, the goroutine waiting for ctx stops as well.
+func (p *pubsub) SubscribeUntil(ctx context.Context, topic string, handler func(...any)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	removed, stop := context.WithCancel(context.Background())
+	cancel, err := p.subscribe(topic, &subscription{handler: handler, stop: stop})
+	if err != nil {
+		stop()
+		return err
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-removed.Done():
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,79 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubscribeUntil(t *testing.T) {
+	ps := New()
+	var calls atomic.Int64
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := ps.SubscribeUntil(ctx, "testTopic", func(args ...any) {
+		calls.Add(1)
+	}); err != nil {
+		t.Fatalf("SubscribeUntil returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic", "first"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for ps.Snapshot()["testTopic"] != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("handler was not removed after the context was cancelled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := ps.Publish("testTopic", "second"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected the handler to be called once, got %d", n)
+	}
+}
+
+func TestSubscribeUntilDone(t *testing.T) {
+	ps := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := ps.SubscribeUntil(ctx, "testTopic", func(args ...any) {}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if n := ps.Snapshot()["testTopic"]; n != 0 {
+		t.Errorf("expected no handlers, got %d", n)
+	}
+}
+
+func TestSubscribeUntilUnsubscribed(t *testing.T) {
+	ps := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 10; i++ {
+		if err := ps.SubscribeUntil(ctx, "testTopic", func(args ...any) {}); err != nil {
+			t.Fatalf("SubscribeUntil returned an error: %s", err.Error())
+		}
+	}
+	if err := ps.Unsubscribe("testTopic"); err != nil {
+		t.Fatalf("Unsubscribe returned an error: %s", err.Error())
+	}
+
+	// The goroutines waiting for ctx must exit even though it is never cancelled.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d goroutines, got %d", before, runtime.NumGoroutine())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
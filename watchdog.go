@@ -0,0 +1,12 @@
+package pubsub
+
+// watch starts the slow handler watchdog for the handler at index, which calls the
+// slow handler callback if the handler is still running after the threshold. The
+// returned Timer must be stopped when the handler returns.
+func (t *topic) watch(index int) Timer {
+	clock := t.p.opts.clock
+	start := clock.Now()
+	return clock.AfterFunc(t.p.opts.slowHandlerThreshold, func() {
+		t.p.opts.slowHandler(t.name, index, clock.Now().Sub(start))
+	})
+}
@@ -0,0 +1,55 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+)
+
+type slowHandlerWarning struct {
+	topic   string
+	index   int
+	elapsed time.Duration
+}
+
+func TestSlowHandlerWarning(t *testing.T) {
+	warnings := make(chan slowHandlerWarning, 2)
+	ps := New(WithSlowHandlerWarning(10*time.Millisecond, func(topic string, index int, elapsed time.Duration) {
+		warnings <- slowHandlerWarning{topic, index, elapsed}
+	}))
+	err := ps.Subscribe("testTopic", func(args ...any) {})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	finished := false
+	err = ps.Subscribe("testTopic", func(args ...any) {
+		time.Sleep(50 * time.Millisecond)
+		finished = true
+	})
+	if err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("testTopic"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+
+	if !finished {
+		t.Fatal("expected the slow handler to keep running")
+	}
+	select {
+	case w := <-warnings:
+		if w.topic != "testTopic" || w.index != 1 {
+			t.Fatalf("expected a warning for handler 1 of testTopic, got handler %d of %q", w.index, w.topic)
+		}
+		if w.elapsed < 10*time.Millisecond {
+			t.Fatalf("expected the elapsed time to be at least the threshold, got %s", w.elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a slow handler warning")
+	}
+	select {
+	case w := <-warnings:
+		t.Fatalf("expected a single warning, also got one for handler %d", w.index)
+	default:
+	}
+}
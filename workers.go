@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// invokeParallel calls the handlers of subs from up to workers goroutines
+// and returns when all of them have finished. See invokeAll for partition and invoke for errs.
+func (t *topic) invokeParallel(subs []*subscription, args []any, errs []error, partition, workers int) {
+	if workers > len(subs) {
+		workers = len(subs)
+	}
+	depth := t.handOffDepth()
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			atDepth(depth, func() {
+				for {
+					i := int(next.Add(1)) - 1
+					if i >= len(subs) {
+						return
+					}
+					t.invokeOne(i, subs[i], args, errs, partition)
+				}
+			})
+		}()
+	}
+	wg.Wait()
+}
+
+// invokeOnGoroutine calls f on a new goroutine, at the publish depth of the calling one,
+// and returns when it has finished. A panic of a handler is raised again on the calling
+// goroutine.
+func (t *topic) invokeOnGoroutine(f func()) {
+	depth := t.handOffDepth()
+	done := make(chan any, 1)
+	go func() {
+		defer func() {
+			done <- recover()
+		}()
+		atDepth(depth, f)
+	}()
+	if r := <-done; r != nil {
+		panic(r)
+	}
+}
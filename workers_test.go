@@ -0,0 +1,163 @@
+package pubsub
+
+import (
+	"bytes"
+	"errors"
+	"hash/fnv"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkers(t *testing.T) {
+	ps := New(WithWorkers(4))
+	const numHandlers = 20
+
+	var mu sync.Mutex
+	called := make(map[int]int)
+	for i := 0; i < numHandlers; i++ {
+		i := i
+		if err := ps.Subscribe("testTopic", func(args ...any) {
+			mu.Lock()
+			called[i] += args[0].(int)
+			mu.Unlock()
+		}); err != nil {
+			t.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	for n := 1; n <= 2; n++ {
+		if err := ps.Publish("testTopic", 1); err != nil {
+			t.Fatalf("Publish returned an error: %s", err.Error())
+		}
+		// Publish returns only after all handlers ran.
+		mu.Lock()
+		for i := 0; i < numHandlers; i++ {
+			if called[i] != n {
+				t.Errorf("expected handler %d to be called %d times, got %d", i, n, called[i])
+			}
+		}
+		mu.Unlock()
+	}
+}
+
+// benchmarkFanOut publishes to a topic with many CPU-bound handlers.
+func benchmarkFanOut(b *testing.B, ps PubSub) {
+	for i := 0; i < 16; i++ {
+		if err := ps.Subscribe("testTopic", func(args ...any) {
+			h := fnv.New64a()
+			for j := 0; j < 1000; j++ {
+				h.Write([]byte(strconv.Itoa(j)))
+			}
+		}); err != nil {
+			b.Fatalf("Subscribe returned an error: %s", err.Error())
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ps.Publish("testTopic", i)
+	}
+}
+
+func BenchmarkFanOutSequential(b *testing.B) {
+	benchmarkFanOut(b, New())
+}
+
+func BenchmarkFanOutWorkers(b *testing.B) {
+	benchmarkFanOut(b, New(WithWorkers(4)))
+}
+
+// goid returns the ID of the calling goroutine, parsed from its stack trace.
+func goid() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	id, _ := strconv.ParseUint(string(buf[:bytes.IndexByte(buf, ' ')]), 10, 64)
+	return id
+}
+
+func TestDeliveryGoroutine(t *testing.T) {
+	ps := New(WithDeliveryGoroutine())
+	var handlerGoroutine uint64
+	finished := false
+	if err := ps.Subscribe("testTopic", func(args ...any) {
+		handlerGoroutine = goid()
+		time.Sleep(20 * time.Millisecond)
+		finished = true
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("testTopic"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if !finished {
+		t.Fatal("expected Publish to return after the handler finished")
+	}
+	if caller := goid(); handlerGoroutine == 0 || handlerGoroutine == caller {
+		t.Fatalf("expected the handler to run on another goroutine than %d, got %d", caller, handlerGoroutine)
+	}
+}
+
+func TestDeliveryGoroutineInline(t *testing.T) {
+	ps := New()
+	var handlerGoroutine uint64
+	if err := ps.Subscribe("testTopic", func(args ...any) { handlerGoroutine = goid() }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	if err := ps.Publish("testTopic"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if caller := goid(); handlerGoroutine != caller {
+		t.Fatalf("expected the handler to run on the publishing goroutine %d by default, got %d", caller, handlerGoroutine)
+	}
+}
+
+func TestDeliveryGoroutineErrorsAndPanics(t *testing.T) {
+	ps := New(WithDeliveryGoroutine())
+	errFailed := errors.New("failed")
+	if _, err := ps.SubscribeErr("errors", func(args ...any) error { return errFailed }); err != nil {
+		t.Fatalf("SubscribeErr returned an error: %s", err.Error())
+	}
+	if err := ps.TryPublish("errors"); !errors.Is(err, errFailed) {
+		t.Fatalf("expected the handler's error from TryPublish, got %v", err)
+	}
+
+	if err := ps.Subscribe("panics", func(args ...any) { panic("boom") }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Fatalf("expected the handler's panic on the publishing goroutine, got %v", r)
+		}
+	}()
+	_ = ps.Publish("panics")
+	t.Fatal("expected Publish to panic")
+}
+
+func TestDeliveryGoroutinePublishLoop(t *testing.T) {
+	ps := New(WithDeliveryGoroutine(), WithMaxPublishDepth(8))
+	var calls int
+	var err error
+	if err := ps.Subscribe("testTopic", func(args ...any) {
+		calls++
+		if e := ps.Publish("testTopic"); e != nil {
+			err = e
+		}
+	}); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	if err := ps.Publish("testTopic"); err != nil {
+		t.Fatalf("Publish returned an error: %s", err.Error())
+	}
+	if calls != 8 {
+		t.Errorf("expected 8 nested deliveries, got %d", calls)
+	}
+	if !errors.Is(err, ErrPublishLoop) {
+		t.Errorf("expected ErrPublishLoop, got %v", err)
+	}
+}
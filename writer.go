@@ -0,0 +1,39 @@
+package pubsub
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// TopicWriter returns an io.Writer that publishes each line written to it to the topic,
+// as a single string arg without the trailing newline. A partial line is buffered until
+// a later write completes it.
+func TopicWriter(ps PubSub, topic string) io.Writer {
+	return &topicWriter{ps: ps, topic: topic}
+}
+
+type topicWriter struct {
+	ps    PubSub
+	topic string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *topicWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(w.buf[:i])
+		w.buf = w.buf[i+1:]
+		if err := w.ps.Publish(w.topic, line); err != nil {
+			return len(p), err
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package pubsub
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestTopicWriter(t *testing.T) {
+	ps := New()
+	var lines []any
+	if err := ps.Subscribe("logs", func(args ...any) { lines = append(lines, args...) }); err != nil {
+		t.Fatalf("Subscribe returned an error: %s", err.Error())
+	}
+
+	w := TopicWriter(ps, "logs")
+	for _, s := range []string{"first line\nsecond ", "line\n", "third", " line\nunfinished"} {
+		n, err := fmt.Fprint(w, s)
+		if err != nil {
+			t.Fatalf("Write returned an error: %s", err.Error())
+		}
+		if n != len(s) {
+			t.Errorf("expected Write to return %d, got %d", len(s), n)
+		}
+	}
+
+	want := []any{"first line", "second line", "third line"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("expected lines %q, got %q", want, lines)
+	}
+}